@@ -0,0 +1,94 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+// Copyright (C) 2025 Lenucksi
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// AmbientPath is the default path for Ambient Weather "custom server" uploads.
+// The station appends its query string directly to the configured path.
+const AmbientPath = "/endpoint"
+
+// ambientFields maps Ambient Weather protocol field names to their Weather
+// Underground equivalents. Fields not listed here are ignored.
+var ambientFields = map[string]string{
+	"dateutc":        "dateutc",
+	"stationtype":    "softwaretype",
+	"tempf":          "tempf",
+	"humidity":       "humidity",
+	"tempinf":        "indoortempf",
+	"humidityin":     "indoorhumidity",
+	"baromrelin":     "baromin",
+	"baromabsin":     "absbaromin",
+	"winddir":        "winddir",
+	"windspeedmph":   "windspeedmph",
+	"windgustmph":    "windgustmph",
+	"maxdailygust":   "maxdailygust",
+	"hourlyrainin":   "rainin",
+	"eventrainin":    "eventrainin",
+	"dailyrainin":    "dailyrainin",
+	"24hourrainin":   "rain24hin",
+	"weeklyrainin":   "weeklyrainin",
+	"monthlyrainin":  "monthlyrainin",
+	"yearlyrainin":   "yearlyrainin",
+	"solarradiation": "solarRadiation",
+	"uv":             "UV",
+	"battout":        "battout",
+	"battin":         "battin",
+	"batt_25":        "batt_25",
+	"batt_lightning": "batt_lightning",
+}
+
+// Ambient Weather numbers its extra sensor channels from 1.
+func init() {
+	for n := 1; n <= MaxChannels; n++ {
+		ambientFields[fmt.Sprintf("temp%df", n)] = channelParam("temperature", n)
+		ambientFields[fmt.Sprintf("humidity%d", n)] = channelParam("humidity", n)
+		ambientFields[fmt.Sprintf("soiltemp%df", n)] = channelParam("soil_temperature", n)
+		ambientFields[fmt.Sprintf("soilhum%d", n)] = channelParam("soil_moisture", n)
+		ambientFields[fmt.Sprintf("batt%d", n)] = channelParam("battery", n)
+		ambientFields[fmt.Sprintf("battsm%d", n)] = channelParam("soil_battery", n)
+	}
+}
+
+// AmbientHandler handles query-string uploads using the Ambient Weather protocol.
+type AmbientHandler struct {
+	weather *WeatherHandler
+}
+
+// NewAmbientHandler creates a new Ambient Weather handler publishing through weather.
+func NewAmbientHandler(weather *WeatherHandler) *AmbientHandler {
+	return &AmbientHandler{weather: weather}
+}
+
+// ServeHTTP handles the Ambient Weather upload endpoint.
+func (h *AmbientHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	slog.Debug("Received Ambient Weather upload", "path", r.URL.Path,
+		"mac", query.Get("MAC"), "stationtype", query.Get("stationtype"))
+
+	queuedCount := h.weather.process(translateParams(query, ambientFields))
+	slog.Info("Processed Ambient Weather update", "sensors_queued", queuedCount)
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	_, _ = fmt.Fprint(w, "success")
+}
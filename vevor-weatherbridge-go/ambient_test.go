@@ -0,0 +1,115 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+// Copyright (C) 2025 Lenucksi
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestTranslateParamsAmbient(t *testing.T) {
+	query := url.Values{
+		"MAC":          {"00:0E:C6:20:0F:7B"},
+		"stationtype":  {"AMBWeatherV4.2.9"},
+		"dateutc":      {"now"},
+		"tempf":        {"66.9"},
+		"baromrelin":   {"29.932"},
+		"baromabsin":   {"29.404"},
+		"hourlyrainin": {"0.000"},
+		"eventrainin":  {"0.118"},
+		"battout":      {"1"},
+		"batt1":        {"1"},
+	}
+
+	params := translateParams(query, ambientFields)
+
+	expected := map[string]string{
+		"dateutc":      "now",
+		"softwaretype": "AMBWeatherV4.2.9",
+		"tempf":        "66.9",
+		"baromin":      "29.932",
+		"rainin":       "0.000",
+		"eventrainin":  "0.118",
+		"battout":      "1",
+		"batt1":        "1",
+	}
+
+	for param, want := range expected {
+		if got := params.Get(param); got != want {
+			t.Errorf("params[%q] = %q, want %q", param, got, want)
+		}
+	}
+
+	if params.Has("MAC") {
+		t.Error("params should not contain the station MAC")
+	}
+}
+
+func TestAmbientFieldsMapToKnownSensors(t *testing.T) {
+	for field, param := range ambientFields {
+		if param == "dateutc" {
+			continue
+		}
+		if !knownQueryParam(param) {
+			t.Errorf("Ambient field %q maps to %q, which has no sensor definition", field, param)
+		}
+	}
+}
+
+func TestAmbientHandler(t *testing.T) {
+	// Warnings are logged for values that fail to parse
+	var logs bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelWarn})))
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	// As uploaded by an Ambient Weather WS-2902 with a WH31 and a soil sensor
+	target := AmbientPath + "?MAC=00:0E:C6:20:0F:7B&stationtype=AMBWeatherV4.2.9&dateutc=now" +
+		"&tempinf=71.1&humidityin=35&baromrelin=29.932&baromabsin=29.404&tempf=66.9&battout=1" +
+		"&humidity=30&winddir=277&windspeedmph=0.0&windgustmph=0.0&maxdailygust=3.4" +
+		"&hourlyrainin=0.000&eventrainin=0.000&dailyrainin=0.000&weeklyrainin=0.000" +
+		"&monthlyrainin=0.000&totalrainin=0.000&solarradiation=0.00&uv=0" +
+		"&temp1f=68.4&humidity1=41&batt1=1&soiltemp1f=54.5&soilhum1=27&battsm1=0&battin=1&batt_co2=1"
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+
+	states := serveUpload(t, func(weather *WeatherHandler) http.Handler { return NewAmbientHandler(weather) }, r)
+
+	if logs.Len() != 0 {
+		t.Errorf("warnings logged:\n%s", logs.String())
+	}
+	expected := map[string]string{
+		"temperature":          "19.4",
+		"station_software":     "AMBWeatherV4.2.9",
+		"parse_errors":         "0",
+		"outdoor_battery":      "1",
+		"indoor_battery":       "1",
+		"battery_ch1":          "1",
+		"soil_battery_ch1":     "0",
+		"temperature_ch1":      "20.2",
+		"soil_moisture_ch1":    "27",
+		"soil_temperature_ch1": "12.5",
+	}
+	for id, want := range expected {
+		if got := states[id]; got != want {
+			t.Errorf("%s = %q, want %q", id, got, want)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+// Copyright (C) 2025 Lenucksi
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Topic styles supported by a broker.
+const (
+	// TopicStyleDiscovery publishes Home Assistant MQTT Discovery topics.
+	TopicStyleDiscovery = "discovery"
+	// TopicStyleFlat publishes plain <prefix>/<device>/<sensor> topics
+	// without discovery configs.
+	TopicStyleFlat = "flat"
+)
+
+// State modes supported by a broker.
+const (
+	// StateModeTopics publishes a state and attributes topic per sensor.
+	StateModeTopics = "topics"
+	// StateModeJSON publishes one JSON document per update holding all
+	// states, with each sensor's attributes nested under "attributes".
+	StateModeJSON = "json"
+)
+
+// PrimaryBrokerName is the name of the broker configured by the mqtt_* options.
+const PrimaryBrokerName = "primary"
+
+// BrokerConfig holds the connection and publishing settings of one broker.
+type BrokerConfig struct {
+	Name        string `json:"name"`
+	URL         string `json:"url"`
+	Host        string `json:"host"`
+	Port        int    `json:"port"`
+	User        string `json:"user"`
+	Password    string `json:"password"`
+	Prefix      string `json:"prefix"`
+	TopicStyle  string `json:"topic_style"`
+	StateMode   string `json:"state_mode"`
+	QoS         int    `json:"qos"`
+	Version     int    `json:"-"` // a number or, from the add-on options, a string
+	TLS         bool   `json:"tls"`
+	CAFile      string `json:"ca_file"`
+	ClientCert  string `json:"client_cert"`
+	ClientKey   string `json:"client_key"`
+	ServerName  string `json:"server_name"`
+	TLSInsecure bool   `json:"tls_insecure"`
+
+	// Per message kind topic template, QoS and retain overrides
+	Messages map[string]MessageOverride `json:"-"`
+}
+
+// PrimaryBroker returns the broker configured by the mqtt_* options.
+func (c *Config) PrimaryBroker() BrokerConfig {
+	return BrokerConfig{
+		Name:        PrimaryBrokerName,
+		URL:         c.MQTTURL,
+		Host:        c.MQTTHost,
+		Port:        c.MQTTPort,
+		User:        c.MQTTUser,
+		Password:    c.MQTTPassword,
+		Prefix:      c.MQTTPrefix,
+		TopicStyle:  TopicStyleDiscovery,
+		StateMode:   c.MQTTStateMode,
+		QoS:         c.MQTTQoS,
+		Version:     c.MQTTVersion,
+		TLS:         c.MQTTTLS,
+		CAFile:      c.MQTTCAFile,
+		ClientCert:  c.MQTTClientCert,
+		ClientKey:   c.MQTTClientKey,
+		ServerName:  c.MQTTServerName,
+		TLSInsecure: c.MQTTTLSInsecure,
+		Messages:    c.MQTTMessages,
+	}
+}
+
+// parseExtraBrokers parses a JSON list of additional brokers. Omitted fields
+// default to port 1883, the primary prefix, discovery topics, a state topic
+// per sensor, QoS 1 and MQTT 3.1.1.
+// Per-kind overrides use the same keys as the primary broker's options
+// (e.g. "state_topic", "state_qos", "state_retain"). Invalid entries are
+// skipped.
+func parseExtraBrokers(value, defaultPrefix string) []BrokerConfig {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+
+	var entries []json.RawMessage
+	if err := json.Unmarshal([]byte(value), &entries); err != nil {
+		slog.Warn("Ignoring invalid extra MQTT brokers", "error", err)
+		return nil
+	}
+
+	var brokers []BrokerConfig
+	names := map[string]bool{PrimaryBrokerName: true}
+	for i, entry := range entries {
+		b := BrokerConfig{
+			Port:       1883,
+			Prefix:     defaultPrefix,
+			TopicStyle: TopicStyleDiscovery,
+			StateMode:  StateModeTopics,
+			QoS:        1,
+			Version:    MQTTVersion3,
+		}
+		if err := json.Unmarshal(entry, &b); err != nil {
+			slog.Warn("Ignoring invalid extra MQTT broker", "index", i, "error", err)
+			continue
+		}
+		var fields map[string]interface{}
+		if err := json.Unmarshal(entry, &fields); err != nil {
+			slog.Warn("Ignoring invalid extra MQTT broker", "index", i, "error", err)
+			continue
+		}
+		if v, ok := fields["version"]; ok && v != nil {
+			version, err := strconv.Atoi(fmt.Sprint(v))
+			if err != nil {
+				slog.Warn("Ignoring extra MQTT broker with invalid MQTT version", "index", i, "version", v)
+				continue
+			}
+			b.Version = version
+		}
+		b.Messages = messageOverrides(func(key string) string {
+			if v, ok := fields[key]; ok && v != nil {
+				return fmt.Sprint(v)
+			}
+			return ""
+		})
+
+		b.Name = strings.TrimSpace(b.Name)
+		b.TopicStyle = strings.ToLower(b.TopicStyle)
+		b.StateMode = strings.ToLower(b.StateMode)
+
+		switch {
+		case !validBrokerName(b.Name) || names[b.Name]:
+			slog.Warn("Ignoring extra MQTT broker without a unique name (letters, digits, - and _)", "index", i, "name", b.Name)
+			continue
+		case b.URL == "" && b.Host == "":
+			slog.Warn("Ignoring extra MQTT broker without url or host", "name", b.Name)
+			continue
+		case b.TopicStyle != TopicStyleDiscovery && b.TopicStyle != TopicStyleFlat:
+			slog.Warn("Ignoring extra MQTT broker with invalid topic style", "name", b.Name, "topic_style", b.TopicStyle)
+			continue
+		case b.StateMode != StateModeTopics && b.StateMode != StateModeJSON:
+			slog.Warn("Ignoring extra MQTT broker with invalid state mode", "name", b.Name, "state_mode", b.StateMode)
+			continue
+		case b.QoS < 0 || b.QoS > 2:
+			slog.Warn("Ignoring extra MQTT broker with invalid QoS", "name", b.Name, "qos", b.QoS)
+			continue
+		case b.Version != MQTTVersion3 && b.Version != MQTTVersion5:
+			slog.Warn("Ignoring extra MQTT broker with invalid MQTT version", "name", b.Name, "version", b.Version)
+			continue
+		}
+
+		names[b.Name] = true
+		brokers = append(brokers, b)
+	}
+	return brokers
+}
+
+// validBrokerName reports whether name is usable as a broker name. Names are
+// part of file names, so only letters, digits, "-" and "_" are allowed.
+func validBrokerName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// Brokers is the set of brokers readings are published to. Each broker has
+// its own client and publish queue, so a slow or unreachable broker does not
+// hold back the others.
+type Brokers struct {
+	clients []*MQTTClient
+	queues  []*PublishQueue
+}
+
+// ConnectBrokers connects to all configured brokers and starts their queues.
+func ConnectBrokers(cfg *Config) (*Brokers, error) {
+	b := &Brokers{}
+	for _, broker := range cfg.Brokers {
+		client, err := NewMQTTClient(cfg, broker)
+		if err != nil {
+			b.Close()
+			return nil, fmt.Errorf("broker %s: %w", broker.Name, err)
+		}
+		b.clients = append(b.clients, client)
+		b.queues = append(b.queues, NewPublishQueue(client, cfg.MQTTQueueSize, cfg.MQTTDropPolicy, cfg.MQTTLateAfter))
+	}
+	return b, nil
+}
+
+// Enqueue queues a reading for every broker. Returns false if no broker
+// accepted it.
+func (b *Brokers) Enqueue(reading Reading) bool {
+	accepted := false
+	for _, q := range b.queues {
+		if q.Enqueue(reading) {
+			accepted = true
+		}
+	}
+	return accepted
+}
+
+// SetStationOnline publishes the station status to every broker.
+func (b *Brokers) SetStationOnline(online bool) {
+	for _, client := range b.clients {
+		client.SetStationOnline(online)
+	}
+}
+
+// BrokerStatus holds the connection state and queue counters of a broker.
+type BrokerStatus struct {
+	Name      string
+	Connected bool
+	Queue     QueueStats
+}
+
+// Status returns the status of every broker, primary first.
+func (b *Brokers) Status() []BrokerStatus {
+	status := make([]BrokerStatus, len(b.clients))
+	for i, client := range b.clients {
+		status[i] = BrokerStatus{
+			Name:      client.broker.Name,
+			Connected: client.IsConnected(),
+			Queue:     b.queues[i].Stats(),
+		}
+	}
+	return status
+}
+
+// Close drains the publish queues and disconnects from all brokers.
+func (b *Brokers) Close() {
+	var wg sync.WaitGroup
+	for i, q := range b.queues {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.Close(5 * time.Second)
+			b.clients[i].Close()
+		}()
+	}
+	wg.Wait()
+}
+
+// bufferPath returns the path of a broker's offline buffer.
+func bufferPath(cfg *Config, broker BrokerConfig) string {
+	if broker.Name == PrimaryBrokerName {
+		return filepath.Join(cfg.DataDir, "buffer.jsonl")
+	}
+	return filepath.Join(cfg.DataDir, fmt.Sprintf("buffer-%s.jsonl", broker.Name))
+}
@@ -0,0 +1,113 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+// Copyright (C) 2025 Lenucksi
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseExtraBrokers(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []BrokerConfig
+	}{
+		{"empty", "", nil},
+		{"invalid JSON", "{", nil},
+		{
+			"defaults",
+			`[{"name": "data", "host": "10.0.0.5"}]`,
+			[]BrokerConfig{{Name: "data", Host: "10.0.0.5", Port: 1883, Prefix: "homeassistant", TopicStyle: TopicStyleDiscovery, StateMode: StateModeTopics, QoS: 1, Version: MQTTVersion3}},
+		},
+		{
+			"all fields",
+			`[{"name": "team", "url": "wss://mqtt.example.com/mqtt", "user": "u", "password": "p",
+			   "prefix": "weather", "topic_style": "FLAT", "state_mode": "json", "qos": 0, "version": 5, "ca_file": "/ssl/ca.crt",
+			   "state_qos": 2, "attributes_retain": false, "state_topic": "team/{{.DeviceID}}"}]`,
+			[]BrokerConfig{{
+				Name: "team", URL: "wss://mqtt.example.com/mqtt", Port: 1883, User: "u", Password: "p",
+				Prefix: "weather", TopicStyle: TopicStyleFlat, StateMode: StateModeJSON, QoS: 0, Version: MQTTVersion5, CAFile: "/ssl/ca.crt",
+				Messages: map[string]MessageOverride{
+					KindState:      {Topic: "team/{{.DeviceID}}", QoS: ptr(2)},
+					KindAttributes: {Retain: ptr(false)},
+				},
+			}},
+		},
+		{
+			"version from add-on options",
+			`[{"name": "data", "host": "10.0.0.5", "version": "5"}]`,
+			[]BrokerConfig{{Name: "data", Host: "10.0.0.5", Port: 1883, Prefix: "homeassistant", TopicStyle: TopicStyleDiscovery, StateMode: StateModeTopics, QoS: 1, Version: MQTTVersion5}},
+		},
+		{
+			"invalid entries skipped",
+			`[{"name": "primary", "host": "a"}, {"name": "no-host"}, {"name": "../x", "host": "b"},
+			  {"name": "style", "host": "c", "topic_style": "other"}, {"name": "mode", "host": "c", "state_mode": "other"}, {"name": "qos", "host": "d", "qos": 3},
+			  {"name": "version", "host": "d", "version": 4}, {"name": "version-text", "host": "d", "version": "five"},
+			  {"name": "ok", "host": "e"}, {"name": "ok", "host": "f"}, "not an object"]`,
+			[]BrokerConfig{{Name: "ok", Host: "e", Port: 1883, Prefix: "homeassistant", TopicStyle: TopicStyleDiscovery, StateMode: StateModeTopics, QoS: 1, Version: MQTTVersion3}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseExtraBrokers(tt.input, "homeassistant")
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("parseExtraBrokers() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPrimaryBroker(t *testing.T) {
+	cfg := &Config{MQTTHost: "core-mosquitto", MQTTPort: 1883, MQTTUser: "u", MQTTPrefix: "ha", MQTTQoS: 2, MQTTVersion: 5, MQTTTLS: true}
+	got := cfg.PrimaryBroker()
+	want := BrokerConfig{
+		Name: PrimaryBrokerName, Host: "core-mosquitto", Port: 1883, User: "u",
+		Prefix: "ha", TopicStyle: TopicStyleDiscovery, QoS: 2, Version: MQTTVersion5, TLS: true,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PrimaryBroker() = %+v, want %+v", got, want)
+	}
+}
+
+func TestBrokersEnqueueFansOut(t *testing.T) {
+	first, second := newFakePublisher(), newFakePublisher()
+	close(first.release)
+	close(second.release)
+	b := &Brokers{queues: []*PublishQueue{
+		NewPublishQueue(first, 10, DropOldest, time.Minute),
+		NewPublishQueue(second, 10, DropOldest, time.Minute),
+	}}
+
+	if !b.Enqueue(readingAt(0)) {
+		t.Fatal("Enqueue() = false, want true")
+	}
+	for _, q := range b.queues {
+		q.Close(time.Second)
+	}
+
+	if len(first.readings) != 1 || len(second.readings) != 1 {
+		t.Errorf("published %d and %d readings, want 1 each", len(first.readings), len(second.readings))
+	}
+}
+
+// ptr returns a pointer to v.
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,230 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+// Copyright (C) 2025 Lenucksi
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// OfflineBuffer stores readings on disk while the broker is unreachable, one
+// JSON document per line, so they survive a restart of the add-on. Only the
+// sensor IDs are stored, definitions are resolved again when loading.
+//
+// When full, the oldest readings are discarded. To avoid rewriting the file on
+// every append, the file may grow a little past capacity and is trimmed in
+// batches; the surplus lines are never returned.
+type OfflineBuffer struct {
+	path     string
+	capacity int
+	lines    int // readings in the file, up to capacity plus one batch
+	mu       sync.Mutex
+}
+
+// bufferedReading is the on-disk form of a Reading.
+type bufferedReading struct {
+	ReceivedAt time.Time       `json:"received_at"`
+	Values     []bufferedValue `json:"values"`
+}
+
+// bufferedValue is the on-disk form of a SensorValue.
+type bufferedValue struct {
+	ID         string                 `json:"id"`
+	State      string                 `json:"state"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// NewOfflineBuffer creates a buffer holding up to capacity readings in the
+// file at path. Readings left over from a previous run are kept.
+func NewOfflineBuffer(path string, capacity int) *OfflineBuffer {
+	b := &OfflineBuffer{path: path, capacity: capacity}
+
+	readings, err := b.load()
+	if err != nil {
+		slog.Warn("Failed to read offline buffer", "path", path, "error", err)
+	}
+	b.lines = len(readings)
+	if len(readings) > 0 {
+		slog.Info("Restored offline buffer", "path", path, "readings", b.count())
+	}
+
+	return b
+}
+
+// Len returns the number of buffered readings.
+func (b *OfflineBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.count()
+}
+
+// Append adds a reading to the end of the buffer.
+func (b *OfflineBuffer) Append(reading Reading) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.lines == b.capacity {
+		slog.Warn("Offline buffer full, dropping oldest readings", "capacity", b.capacity)
+	}
+	if b.lines >= b.capacity+b.batch() {
+		readings, err := b.load()
+		if err != nil {
+			return err
+		}
+		return b.write(append(readings, reading))
+	}
+
+	data, err := json.Marshal(compactReading(reading))
+	if err != nil {
+		return fmt.Errorf("failed to marshal reading: %w", err)
+	}
+
+	f, err := os.OpenFile(b.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open offline buffer: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write offline buffer: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write offline buffer: %w", err)
+	}
+	b.lines++
+	return nil
+}
+
+// Readings returns the buffered readings, oldest first.
+func (b *OfflineBuffer) Readings() ([]Reading, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.load()
+}
+
+// Replace replaces the buffered readings, e.g. with those not yet replayed.
+func (b *OfflineBuffer) Replace(readings []Reading) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.write(readings)
+}
+
+// count returns the number of readings returned by load. The caller must
+// hold mu.
+func (b *OfflineBuffer) count() int {
+	return min(b.lines, b.capacity)
+}
+
+// batch returns the number of readings the file may hold past capacity
+// before it is trimmed.
+func (b *OfflineBuffer) batch() int {
+	return max(b.capacity/10, 1)
+}
+
+// load reads the newest readings from disk, up to capacity, skipping lines
+// that cannot be parsed. The caller must hold mu.
+func (b *OfflineBuffer) load() ([]Reading, error) {
+	data, err := os.ReadFile(b.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read offline buffer: %w", err)
+	}
+
+	var readings []Reading
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for scanner.Scan() {
+		var buffered bufferedReading
+		if err := json.Unmarshal(scanner.Bytes(), &buffered); err != nil {
+			slog.Warn("Skipping unreadable buffered reading", "path", b.path, "error", err)
+			continue
+		}
+		readings = append(readings, b.expand(buffered))
+	}
+	if len(readings) > b.capacity {
+		readings = readings[len(readings)-b.capacity:]
+	}
+	return readings, nil
+}
+
+// write replaces the buffer file atomically, removing it when readings is
+// empty. The caller must hold mu.
+func (b *OfflineBuffer) write(readings []Reading) error {
+	if len(readings) > b.capacity {
+		readings = readings[len(readings)-b.capacity:]
+	}
+	if len(readings) == 0 {
+		b.lines = 0
+		if err := os.Remove(b.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove offline buffer: %w", err)
+		}
+		return nil
+	}
+
+	var buf bytes.Buffer
+	for _, reading := range readings {
+		data, err := json.Marshal(compactReading(reading))
+		if err != nil {
+			return fmt.Errorf("failed to marshal reading: %w", err)
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+
+	tmp := b.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("failed to write offline buffer: %w", err)
+	}
+	if err := os.Rename(tmp, b.path); err != nil {
+		return fmt.Errorf("failed to replace offline buffer: %w", err)
+	}
+	b.lines = len(readings)
+	return nil
+}
+
+// compactReading converts a reading to its on-disk form.
+func compactReading(reading Reading) bufferedReading {
+	buffered := bufferedReading{ReceivedAt: reading.ReceivedAt, Values: make([]bufferedValue, len(reading.Values))}
+	for i, v := range reading.Values {
+		buffered.Values[i] = bufferedValue{ID: v.Sensor.ID, State: v.State, Attributes: v.Attributes}
+	}
+	return buffered
+}
+
+// expand converts a buffered reading back, skipping values of sensors that
+// no longer exist (e.g. after an update of the add-on).
+func (b *OfflineBuffer) expand(buffered bufferedReading) Reading {
+	reading := Reading{ReceivedAt: buffered.ReceivedAt}
+	for _, v := range buffered.Values {
+		sensor := GetSensorByID(v.ID)
+		if sensor == nil {
+			slog.Debug("Skipping buffered value of unknown sensor", "path", b.path, "sensor", v.ID)
+			continue
+		}
+		reading.Values = append(reading.Values, SensorValue{Sensor: *sensor, State: v.State, Attributes: v.Attributes})
+	}
+	return reading
+}
@@ -0,0 +1,133 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+// Copyright (C) 2025 Lenucksi
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOfflineBuffer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "buffer.jsonl")
+	b := NewOfflineBuffer(path, 3)
+
+	at := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+	for i := range 4 {
+		reading := Reading{
+			ReceivedAt: at.Add(time.Duration(i) * time.Minute),
+			Values:     []SensorValue{{Sensor: *GetSensorByQueryParam("tempf"), State: "20.0"}},
+		}
+		if err := b.Append(reading); err != nil {
+			t.Fatalf("Append() error: %v", err)
+		}
+	}
+
+	// Readings survive a restart, the oldest one was dropped
+	restored := NewOfflineBuffer(path, 3)
+	if got := restored.Len(); got != 3 {
+		t.Fatalf("Len() = %d, want 3", got)
+	}
+	readings, err := restored.Readings()
+	if err != nil {
+		t.Fatalf("Readings() error: %v", err)
+	}
+	for i, r := range readings {
+		want := at.Add(time.Duration(i+1) * time.Minute)
+		if !r.ReceivedAt.Equal(want) {
+			t.Errorf("reading %d received at %v, want %v", i, r.ReceivedAt, want)
+		}
+		if r.Values[0].Sensor.ID != "temperature" || r.Values[0].State != "20.0" {
+			t.Errorf("reading %d values = %+v", i, r.Values)
+		}
+	}
+
+	if err := restored.Replace(readings[2:]); err != nil {
+		t.Fatalf("Replace() error: %v", err)
+	}
+	if got := restored.Len(); got != 1 {
+		t.Errorf("Len() after Replace() = %d, want 1", got)
+	}
+
+	if err := restored.Replace(nil); err != nil {
+		t.Fatalf("Replace(nil) error: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("buffer file still exists after clearing: %v", err)
+	}
+}
+
+func TestOfflineBufferSkipsCorruptLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "buffer.jsonl")
+	data := `{"received_at":"2025-12-01T12:00:00Z","values":[]}` + "\n" +
+		`{"received_at":` + "\n" +
+		`{"received_at":"2025-12-01T12:01:00Z","values":[]}` + "\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := NewOfflineBuffer(path, 10).Len(); got != 2 {
+		t.Errorf("Len() = %d, want 2", got)
+	}
+}
+
+func TestOfflineBufferTrimsInBatches(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "buffer.jsonl")
+	b := NewOfflineBuffer(path, 20)
+
+	at := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+	for i := range 50 {
+		reading := Reading{
+			ReceivedAt: at.Add(time.Duration(i) * time.Minute),
+			Values:     []SensorValue{{Sensor: *GetSensorByQueryParam("tempf"), State: "20.0"}},
+		}
+		if err := b.Append(reading); err != nil {
+			t.Fatalf("Append() error: %v", err)
+		}
+		if got := b.Len(); got != min(i+1, 20) {
+			t.Fatalf("Len() after %d appends = %d", i+1, got)
+		}
+	}
+
+	// The file holds at most one batch past capacity, with sensor IDs only
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines > 22 {
+		t.Errorf("buffer file has %d lines, want at most 22", lines)
+	}
+	if strings.Contains(string(data), `"sensor"`) {
+		t.Errorf("buffer file contains sensor definitions: %s", data)
+	}
+
+	readings, err := NewOfflineBuffer(path, 20).Readings()
+	if err != nil {
+		t.Fatalf("Readings() error: %v", err)
+	}
+	if len(readings) != 20 {
+		t.Fatalf("restored %d readings, want 20", len(readings))
+	}
+	if want := at.Add(30 * time.Minute); !readings[0].ReceivedAt.Equal(want) {
+		t.Errorf("oldest reading received at %v, want %v", readings[0].ReceivedAt, want)
+	}
+	if got := readings[19].Values[0].Sensor; got.ID != "temperature" || got.QueryParam != "tempf" {
+		t.Errorf("restored sensor = %+v", got)
+	}
+}
@@ -0,0 +1,62 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+// Copyright (C) 2025 Lenucksi
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import "net/url"
+
+// conditions returns the states of the binary sensors for a station update,
+// from parameters in Weather Underground names and imperial units. Sensors
+// whose inputs are missing are left out.
+func (h *WeatherHandler) conditions(params url.Values) map[string]string {
+	states := make(map[string]string)
+
+	if rate, ok := paramFloat(params, "rainratein"); ok {
+		states["raining"] = onOff(rate > 0)
+	}
+
+	// Frost is likely when the air or the dew point is near freezing
+	tempF, hasTemp := paramFloat(params, "tempf")
+	dewPointF, hasDewPoint := paramFloat(params, "dewptf")
+	if !hasDewPoint && hasTemp {
+		if humidity, ok := paramFloat(params, "humidity"); ok {
+			dewPointF, hasDewPoint = DewPointF(tempF, humidity), true
+		}
+	}
+	if hasTemp || hasDewPoint {
+		frost := (hasTemp && FToC(tempF) < h.cfg.FrostThreshold) ||
+			(hasDewPoint && FToC(dewPointF) < h.cfg.FrostThreshold)
+		states["frost_risk"] = onOff(frost)
+	}
+
+	if gust, ok := paramFloat(params, "windgustmph"); ok {
+		states["high_wind"] = onOff(MphToKmh(gust) > h.cfg.HighWindThreshold)
+	}
+
+	if radiation, ok := paramFloat(params, "solarRadiation"); ok {
+		states["daylight"] = onOff(radiation > h.cfg.DaylightThreshold)
+	}
+
+	return states
+}
+
+// onOff returns the binary sensor state for a condition.
+func onOff(on bool) string {
+	if on {
+		return "ON"
+	}
+	return "OFF"
+}
@@ -0,0 +1,57 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+// Copyright (C) 2025 Lenucksi
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestConditions(t *testing.T) {
+	h := &WeatherHandler{cfg: &Config{FrostThreshold: 2, HighWindThreshold: 50, DaylightThreshold: 10}}
+
+	tests := []struct {
+		name     string
+		params   url.Values
+		expected map[string]string
+	}{
+		{"no inputs", url.Values{}, map[string]string{}},
+		{
+			"calm dry night",
+			url.Values{"rainratein": {"0"}, "tempf": {"50"}, "dewptf": {"40"}, "windgustmph": {"5"}, "solarRadiation": {"0"}},
+			map[string]string{"raining": "OFF", "frost_risk": "OFF", "high_wind": "OFF", "daylight": "OFF"},
+		},
+		{
+			"stormy day",
+			url.Values{"rainratein": {"0.12"}, "tempf": {"50"}, "windgustmph": {"40"}, "solarRadiation": {"250"}},
+			map[string]string{"raining": "ON", "frost_risk": "OFF", "high_wind": "ON", "daylight": "ON"},
+		},
+		{"cold air", url.Values{"tempf": {"33"}}, map[string]string{"frost_risk": "ON"}},
+		{"dew point below threshold", url.Values{"tempf": {"41"}, "dewptf": {"30"}}, map[string]string{"frost_risk": "ON"}},
+		{"dew point from humidity", url.Values{"tempf": {"40"}, "humidity": {"80"}}, map[string]string{"frost_risk": "ON"}},
+		{"dry air", url.Values{"tempf": {"40"}, "humidity": {"90"}}, map[string]string{"frost_risk": "OFF"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := h.conditions(tt.params); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("conditions() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
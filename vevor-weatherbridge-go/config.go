@@ -29,12 +29,51 @@ type Config struct {
 	// Logging
 	LogLevel slog.Level
 
-	// MQTT configuration
+	// MQTT configuration (MQTTURL, if set, replaces host and port)
+	MQTTURL      string
 	MQTTHost     string
 	MQTTPort     int
 	MQTTUser     string
 	MQTTPassword string
 	MQTTPrefix   string
+	MQTTQoS      int
+
+	// MQTT protocol version (3 for MQTT 3.1.1 or 5)
+	MQTTVersion int
+
+	// MQTT 5 message expiry of state and attributes messages (0 uses the
+	// station timeout)
+	MQTTMessageExpiry time.Duration
+
+	// State publishing mode: a topic per sensor ("topics") or one JSON
+	// document per update ("json")
+	MQTTStateMode string
+
+	// Per message kind topic template, QoS and retain overrides, from
+	// MQTT_<KIND>_TOPIC, MQTT_<KIND>_QOS and MQTT_<KIND>_RETAIN
+	MQTTMessages map[string]MessageOverride
+
+	// MQTT over TLS: CA file (system roots if empty), client certificate and
+	// key, server name override and whether to skip certificate verification
+	MQTTTLS         bool
+	MQTTCAFile      string
+	MQTTClientCert  string
+	MQTTClientKey   string
+	MQTTServerName  string
+	MQTTTLSInsecure bool
+
+	// Publish queue: capacity, drop policy when full (oldest or newest) and
+	// the delay after which a reading counts as late
+	MQTTQueueSize  int
+	MQTTDropPolicy string
+	MQTTLateAfter  time.Duration
+
+	// Number of readings kept on disk while the broker is unreachable (0 disables)
+	MQTTBufferSize int
+
+	// All brokers readings are published to: the primary one configured by
+	// the MQTT* fields, followed by those from MQTT_EXTRA_BROKERS
+	Brokers []BrokerConfig
 
 	// Device identification
 	DeviceID           string
@@ -42,12 +81,44 @@ type Config struct {
 	DeviceManufacturer string
 	DeviceModel        string
 
+	// Area suggested for the station device in Home Assistant (optional)
+	DeviceSuggestedArea string
+
+	// Link to the bridge shown on its device page (optional)
+	ConfigurationURL string
+
 	// Timezone
 	Timezone *time.Location
 
 	// Units (metric or imperial)
 	Units string
 
+	// Station elevation in meters, used to derive relative pressure
+	StationElevation float64
+
+	// Hemisphere of the station (north or south), used for the local forecast
+	Hemisphere string
+
+	// Condition thresholds for the binary sensors, in metric units: frost
+	// risk below FrostThreshold (°C), high wind above HighWindThreshold
+	// (gust, km/h), daylight above DaylightThreshold (W/m²)
+	FrostThreshold    float64
+	HighWindThreshold float64
+	DaylightThreshold float64
+
+	// Friendly names for extra sensor channels, keyed by group and channel (e.g. "temp2")
+	ChannelNames map[string]string
+
+	// Time after which Home Assistant marks sensor values as expired (0 disables)
+	SensorExpireAfter time.Duration
+
+	// Time without station updates after which the station is reported
+	// offline on its own availability topic (0 disables)
+	StationTimeout time.Duration
+
+	// Directory for persistent state (the add-on's /data directory)
+	DataDir string
+
 	// Weather Underground forwarding
 	WUForward  bool
 	WUUsername string
@@ -57,19 +128,46 @@ type Config struct {
 // LoadConfig loads configuration from environment variables with defaults.
 func LoadConfig() *Config {
 	cfg := &Config{
-		LogLevel:           parseLogLevel(getEnv("LOG_LEVEL", "INFO")),
-		MQTTHost:           getEnv("MQTT_HOST", "localhost"),
-		MQTTPort:           getEnvInt("MQTT_PORT", 1883),
-		MQTTUser:           getEnv("MQTT_USER", ""),
-		MQTTPassword:       getEnv("MQTT_PASSWORD", ""),
-		MQTTPrefix:         getEnv("MQTT_PREFIX", "homeassistant"),
-		DeviceName:         getEnv("DEVICE_NAME", "Weather Station"),
-		DeviceManufacturer: getEnv("DEVICE_MANUFACTURER", "VEVOR"),
-		DeviceModel:        getEnv("DEVICE_MODEL", "7-in-1 Weather Station"),
-		Units:              strings.ToLower(getEnv("UNITS", "metric")),
-		WUForward:          getEnvBool("WU_FORWARD", false),
-		WUUsername:         getEnv("WU_USERNAME", ""),
-		WUPassword:         getEnv("WU_PASSWORD", ""),
+		LogLevel:            parseLogLevel(getEnv("LOG_LEVEL", "INFO")),
+		MQTTURL:             getEnv("MQTT_URL", ""),
+		MQTTHost:            getEnv("MQTT_HOST", "localhost"),
+		MQTTPort:            getEnvInt("MQTT_PORT", 1883),
+		MQTTUser:            getEnv("MQTT_USER", ""),
+		MQTTPassword:        getEnv("MQTT_PASSWORD", ""),
+		MQTTPrefix:          getEnv("MQTT_PREFIX", "homeassistant"),
+		MQTTQoS:             getEnvInt("MQTT_QOS", 1),
+		MQTTVersion:         getEnvInt("MQTT_VERSION", MQTTVersion3),
+		MQTTMessageExpiry:   time.Duration(getEnvInt("MQTT_MESSAGE_EXPIRY", 0)) * time.Second,
+		MQTTMessages:        messageOverrides(getMQTTEnv),
+		MQTTStateMode:       strings.ToLower(getEnv("MQTT_STATE_MODE", StateModeTopics)),
+		MQTTTLS:             getEnvBool("MQTT_TLS", false),
+		MQTTCAFile:          getEnv("MQTT_CA_FILE", ""),
+		MQTTClientCert:      getEnv("MQTT_CLIENT_CERT", ""),
+		MQTTClientKey:       getEnv("MQTT_CLIENT_KEY", ""),
+		MQTTServerName:      getEnv("MQTT_SERVER_NAME", ""),
+		MQTTTLSInsecure:     getEnvBool("MQTT_TLS_INSECURE", false),
+		MQTTQueueSize:       getEnvInt("MQTT_QUEUE_SIZE", 100),
+		MQTTDropPolicy:      strings.ToLower(getEnv("MQTT_DROP_POLICY", DropOldest)),
+		MQTTLateAfter:       time.Duration(getEnvInt("MQTT_LATE_AFTER", 30)) * time.Second,
+		MQTTBufferSize:      getEnvInt("MQTT_BUFFER_SIZE", 1000),
+		DeviceName:          getEnv("DEVICE_NAME", "Weather Station"),
+		DeviceManufacturer:  getEnv("DEVICE_MANUFACTURER", "VEVOR"),
+		DeviceModel:         getEnv("DEVICE_MODEL", "7-in-1 Weather Station"),
+		DeviceSuggestedArea: getEnv("DEVICE_SUGGESTED_AREA", ""),
+		ConfigurationURL:    getEnv("CONFIGURATION_URL", ""),
+		Units:               strings.ToLower(getEnv("UNITS", "metric")),
+		ChannelNames:        parseChannelNames(getEnv("CHANNEL_NAMES", "")),
+		StationElevation:    getEnvFloat("STATION_ELEVATION", 0),
+		Hemisphere:          strings.ToLower(getEnv("HEMISPHERE", "north")),
+		FrostThreshold:      getEnvFloat("FROST_THRESHOLD", 2),
+		HighWindThreshold:   getEnvFloat("HIGH_WIND_THRESHOLD", 50),
+		DaylightThreshold:   getEnvFloat("DAYLIGHT_THRESHOLD", 10),
+		SensorExpireAfter:   time.Duration(getEnvInt("SENSOR_EXPIRE_AFTER", 0)) * time.Second,
+		StationTimeout:      time.Duration(getEnvInt("STATION_TIMEOUT", 10)) * time.Minute,
+		DataDir:             getEnv("DATA_DIR", "/data"),
+		WUForward:           getEnvBool("WU_FORWARD", false),
+		WUUsername:          getEnv("WU_USERNAME", ""),
+		WUPassword:          getEnv("WU_PASSWORD", ""),
 	}
 
 	// Derive DeviceID from DeviceName (lowercase, spaces to underscores)
@@ -95,6 +193,43 @@ func LoadConfig() *Config {
 		cfg.Units = "metric"
 	}
 
+	// Validate hemisphere
+	if cfg.Hemisphere != "north" && cfg.Hemisphere != "south" {
+		slog.Warn("Invalid hemisphere, defaulting to north", "hemisphere", cfg.Hemisphere)
+		cfg.Hemisphere = "north"
+	}
+
+	// Validate QoS
+	if cfg.MQTTQoS < 0 || cfg.MQTTQoS > 2 {
+		slog.Warn("Invalid MQTT QoS, defaulting to 1", "qos", cfg.MQTTQoS)
+		cfg.MQTTQoS = 1
+	}
+
+	// Validate protocol version
+	if cfg.MQTTVersion != MQTTVersion3 && cfg.MQTTVersion != MQTTVersion5 {
+		slog.Warn("Invalid MQTT version, defaulting to 3", "version", cfg.MQTTVersion)
+		cfg.MQTTVersion = MQTTVersion3
+	}
+
+	// Validate state mode
+	if cfg.MQTTStateMode != StateModeTopics && cfg.MQTTStateMode != StateModeJSON {
+		slog.Warn("Invalid MQTT state mode, defaulting to topics", "state_mode", cfg.MQTTStateMode)
+		cfg.MQTTStateMode = StateModeTopics
+	}
+
+	// Validate publish queue settings
+	if cfg.MQTTQueueSize < 1 {
+		slog.Warn("Invalid MQTT queue size, defaulting to 100", "size", cfg.MQTTQueueSize)
+		cfg.MQTTQueueSize = 100
+	}
+	if cfg.MQTTDropPolicy != DropOldest && cfg.MQTTDropPolicy != DropNewest {
+		slog.Warn("Invalid MQTT drop policy, defaulting to oldest", "policy", cfg.MQTTDropPolicy)
+		cfg.MQTTDropPolicy = DropOldest
+	}
+
+	cfg.Brokers = append([]BrokerConfig{cfg.PrimaryBroker()},
+		parseExtraBrokers(getEnv("MQTT_EXTRA_BROKERS", ""), cfg.MQTTPrefix)...)
+
 	return cfg
 }
 
@@ -106,6 +241,12 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getMQTTEnv returns the MQTT_ environment variable for a lowercase key,
+// e.g. MQTT_STATE_QOS for "state_qos".
+func getMQTTEnv(key string) string {
+	return os.Getenv("MQTT_" + strings.ToUpper(key))
+}
+
 // getEnvInt returns environment variable as int or default.
 func getEnvInt(key string, defaultValue int) int {
 	if value := os.Getenv(key); value != "" {
@@ -116,6 +257,16 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+// getEnvFloat returns environment variable as float64 or default.
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return defaultValue
+}
+
 // getEnvBool returns environment variable as bool or default.
 func getEnvBool(key string, defaultValue bool) bool {
 	if value := os.Getenv(key); value != "" {
@@ -125,6 +276,27 @@ func getEnvBool(key string, defaultValue bool) bool {
 	return defaultValue
 }
 
+// parseChannelNames parses a comma-separated list of channel names
+// (e.g. "temp2=Greenhouse,soil1=Raised Bed") into a map keyed by channel.
+func parseChannelNames(value string) map[string]string {
+	names := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, name, ok := strings.Cut(entry, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		name = strings.TrimSpace(name)
+		if !ok || key == "" || name == "" {
+			slog.Warn("Ignoring invalid channel name", "entry", entry)
+			continue
+		}
+		names[key] = name
+	}
+	return names
+}
+
 // parseLogLevel converts string log level to slog.Level.
 func parseLogLevel(level string) slog.Level {
 	switch strings.ToUpper(level) {
@@ -0,0 +1,48 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+// Copyright (C) 2025 Lenucksi
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseChannelNames(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected map[string]string
+	}{
+		{"empty", "", map[string]string{}},
+		{"single", "temp2=Greenhouse", map[string]string{"temp2": "Greenhouse"}},
+		{
+			"multiple with spaces",
+			" temp2 = Greenhouse , SOIL1=Raised Bed",
+			map[string]string{"temp2": "Greenhouse", "soil1": "Raised Bed"},
+		},
+		{"invalid entries skipped", "temp2,=Garage,leaf1=", map[string]string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := parseChannelNames(tt.input)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("parseChannelNames(%q) = %v, want %v", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
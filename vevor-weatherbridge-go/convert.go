@@ -18,6 +18,9 @@ package main
 
 import "math"
 
+// hPaPerInHg is the number of hectopascals in one inch of mercury.
+const hPaPerInHg = 33.8639
+
 // FToC converts Fahrenheit to Celsius, rounded to 1 decimal place.
 func FToC(f float64) float64 {
 	return roundTo((f-32)*5.0/9.0, 1)
@@ -25,7 +28,12 @@ func FToC(f float64) float64 {
 
 // InHgToHPa converts inches of mercury to hectopascals, rounded to 1 decimal place.
 func InHgToHPa(inhg float64) float64 {
-	return roundTo(inhg*33.8639, 1)
+	return roundTo(inhg*hPaPerInHg, 1)
+}
+
+// HPaToInHg converts hectopascals to inches of mercury, rounded to 2 decimal places.
+func HPaToInHg(hpa float64) float64 {
+	return roundTo(hpa/hPaPerInHg, 2)
 }
 
 // MphToKmh converts miles per hour to kilometers per hour, rounded to 1 decimal place.
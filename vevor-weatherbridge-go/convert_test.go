@@ -67,6 +67,26 @@ func TestInHgToHPa(t *testing.T) {
 	}
 }
 
+func TestHPaToInHg(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    float64
+		expected float64
+	}{
+		{"standard atmosphere", 1013.25, 29.92},
+		{"tendency", -4.1, -0.12},
+		{"zero", 0.0, 0.0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := HPaToInHg(tt.input); result != tt.expected {
+				t.Errorf("HPaToInHg(%v) = %v, want %v", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
 func TestMphToKmh(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -0,0 +1,160 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+// Copyright (C) 2025 Lenucksi
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math"
+	"net/url"
+	"strconv"
+)
+
+// Magnus formula coefficients (Sonntag 1990), valid from -45 °C to 60 °C.
+const (
+	magnusB = 17.62
+	magnusC = 243.12
+)
+
+// Standard atmosphere lapse rate (K/m) and barometric exponent.
+const (
+	lapseRate          = 0.0065
+	barometricExponent = 5.257
+	standardTempF      = 59.0 // 15 °C, used when no outdoor temperature is reported
+)
+
+// SeaLevelPressure reduces station (absolute) pressure in inHg to sea level
+// (relative) pressure in inHg, using the station elevation in meters and the
+// current outdoor temperature in Fahrenheit.
+func SeaLevelPressure(absInHg, elevationM, tempF float64) float64 {
+	tempK := (tempF-32)*5.0/9.0 + 273.15
+	return absInHg * math.Pow(1-lapseRate*elevationM/(tempK+lapseRate*elevationM), -barometricExponent)
+}
+
+// DewPointF computes the dew point in Fahrenheit from a temperature in
+// Fahrenheit and relative humidity in percent. Returns NaN if humidity is
+// not in the range (0, 100].
+func DewPointF(tempF, humidity float64) float64 {
+	if humidity <= 0 || humidity > 100 {
+		return math.NaN()
+	}
+	tempC := (tempF - 32) * 5.0 / 9.0
+	gamma := math.Log(humidity/100) + magnusB*tempC/(magnusC+tempC)
+	dewC := magnusC * gamma / (magnusB - gamma)
+	return dewC*9.0/5.0 + 32
+}
+
+// HeatIndexF computes the NWS heat index in Fahrenheit from a temperature in
+// Fahrenheit and relative humidity in percent, using the Rothfusz regression
+// with the NWS adjustments and the simple formula for mild conditions.
+func HeatIndexF(tempF, humidity float64) float64 {
+	simple := 0.5 * (tempF + 61.0 + (tempF-68.0)*1.2 + humidity*0.094)
+	if (simple+tempF)/2 < 80 {
+		return simple
+	}
+
+	hi := -42.379 + 2.04901523*tempF + 10.14333127*humidity -
+		0.22475541*tempF*humidity - 0.00683783*tempF*tempF -
+		0.05481717*humidity*humidity + 0.00122874*tempF*tempF*humidity +
+		0.00085282*tempF*humidity*humidity - 0.00000199*tempF*tempF*humidity*humidity
+
+	switch {
+	case humidity < 13 && tempF >= 80 && tempF <= 112:
+		hi -= ((13 - humidity) / 4) * math.Sqrt((17-math.Abs(tempF-95))/17)
+	case humidity > 85 && tempF >= 80 && tempF <= 87:
+		hi += ((humidity - 85) / 10) * ((87 - tempF) / 5)
+	}
+	return hi
+}
+
+// WindChillF computes the NWS wind chill in Fahrenheit from a temperature in
+// Fahrenheit and wind speed in mph. Outside the formula's valid range
+// (above 50 °F or below 3 mph) the air temperature is returned.
+func WindChillF(tempF, windMph float64) float64 {
+	if tempF > 50 || windMph < 3 {
+		return tempF
+	}
+	v := math.Pow(windMph, 0.16)
+	return 35.74 + 0.6215*tempF - 35.75*v + 0.4275*tempF*v
+}
+
+// ApparentTemperatureF computes the Australian (BoM) apparent temperature in
+// Fahrenheit from a temperature in Fahrenheit, relative humidity in percent
+// and wind speed in mph.
+func ApparentTemperatureF(tempF, humidity, windMph float64) float64 {
+	tempC := (tempF - 32) * 5.0 / 9.0
+	windMs := windMph * 0.44704
+	vapourPressure := humidity / 100 * 6.105 * math.Exp(17.27*tempC/(237.7+tempC))
+	apparentC := tempC + 0.33*vapourPressure - 0.70*windMs - 4.00
+	return apparentC*9.0/5.0 + 32
+}
+
+// deriveValues adds values the bridge computes itself to params. Derived values
+// use Weather Underground parameter names and imperial units so they pass
+// through the regular conversion pipeline. Values sent by the station win.
+func (h *WeatherHandler) deriveValues(params url.Values) {
+	if !params.Has("indoordewptf") {
+		tempF, okT := paramFloat(params, "indoortempf")
+		humidity, okH := paramFloat(params, "indoorhumidity")
+		if okT && okH {
+			setDerived(params, "indoordewptf", DewPointF(tempF, humidity))
+		}
+	}
+
+	// Feels-like temperatures from outdoor temperature, humidity and wind speed
+	tempF, hasTemp := paramFloat(params, "tempf")
+	humidity, hasHumidity := paramFloat(params, "humidity")
+	windMph, hasWind := paramFloat(params, "windspeedmph")
+	if hasTemp && hasHumidity && !params.Has("heatindexf") {
+		setDerived(params, "heatindexf", HeatIndexF(tempF, humidity))
+	}
+	if hasTemp && hasWind && !params.Has("windchillf") {
+		setDerived(params, "windchillf", WindChillF(tempF, windMph))
+	}
+	if hasTemp && hasHumidity && hasWind && !params.Has("apparenttempf") {
+		setDerived(params, "apparenttempf", ApparentTemperatureF(tempF, humidity, windMph))
+	}
+
+	// Relative pressure from absolute pressure, if the station only sends the latter
+	if !params.Has("baromin") {
+		if absolute, ok := paramFloat(params, "absbaromin"); ok {
+			if !hasTemp {
+				tempF = standardTempF
+			}
+			setDerived(params, "baromin", SeaLevelPressure(absolute, h.cfg.StationElevation, tempF))
+		}
+	}
+}
+
+// paramFloat returns the numeric value of a parameter, if present and valid.
+func paramFloat(params url.Values, name string) (float64, bool) {
+	raw := params.Get(name)
+	if raw == "" {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, false
+	}
+	return value, true
+}
+
+// setDerived stores a derived value in params, skipping NaN results.
+func setDerived(params url.Values, name string, value float64) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return
+	}
+	params.Set(name, strconv.FormatFloat(value, 'f', 3, 64))
+}
@@ -0,0 +1,216 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+// Copyright (C) 2025 Lenucksi
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math"
+	"net/url"
+	"testing"
+)
+
+func TestDewPointF(t *testing.T) {
+	tests := []struct {
+		name     string
+		tempF    float64
+		humidity float64
+		expected float64
+	}{
+		{"saturated air", 68.0, 100, 68.0},
+		{"typical indoor", 71.6, 45, 49.3},
+		{"humid summer", 86.0, 70, 75.2},
+		{"cold and dry", 32.0, 50, 15.6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := DewPointF(tt.tempF, tt.humidity)
+			if math.Abs(result-tt.expected) > 0.3 {
+				t.Errorf("DewPointF(%v, %v) = %v, want %v", tt.tempF, tt.humidity, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDewPointFInvalidHumidity(t *testing.T) {
+	for _, humidity := range []float64{0, -5, 101} {
+		if result := DewPointF(68.0, humidity); !math.IsNaN(result) {
+			t.Errorf("DewPointF(68, %v) = %v, want NaN", humidity, result)
+		}
+	}
+}
+
+func TestDeriveValuesIndoorDewPoint(t *testing.T) {
+	h := &WeatherHandler{cfg: &Config{Units: "metric"}}
+
+	params := url.Values{"indoortempf": {"71.6"}, "indoorhumidity": {"45"}}
+	h.deriveValues(params)
+	if !params.Has("indoordewptf") {
+		t.Fatal("indoordewptf not derived from indoor temperature and humidity")
+	}
+
+	// Station-provided values take precedence
+	params = url.Values{"indoortempf": {"71.6"}, "indoorhumidity": {"45"}, "indoordewptf": {"50.0"}}
+	h.deriveValues(params)
+	if got := params.Get("indoordewptf"); got != "50.0" {
+		t.Errorf("indoordewptf = %q, want station value 50.0", got)
+	}
+
+	// Missing humidity means nothing to derive
+	params = url.Values{"indoortempf": {"71.6"}}
+	h.deriveValues(params)
+	if params.Has("indoordewptf") {
+		t.Error("indoordewptf should not be derived without indoor humidity")
+	}
+}
+
+func TestSeaLevelPressure(t *testing.T) {
+	tests := []struct {
+		name       string
+		absInHg    float64
+		elevationM float64
+		tempF      float64
+		expected   float64
+	}{
+		{"sea level unchanged", 29.92, 0, 59.0, 29.92},
+		{"500 m standard temperature", 29.53, 500, 59.0, 31.32},
+		{"1000 m cold", 26.50, 1000, 23.0, 30.06},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := SeaLevelPressure(tt.absInHg, tt.elevationM, tt.tempF)
+			if math.Abs(result-tt.expected) > 0.05 {
+				t.Errorf("SeaLevelPressure(%v, %v, %v) = %v, want %v",
+					tt.absInHg, tt.elevationM, tt.tempF, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDeriveValuesRelativePressure(t *testing.T) {
+	h := &WeatherHandler{cfg: &Config{Units: "metric", StationElevation: 500}}
+
+	params := url.Values{"absbaromin": {"29.53"}, "tempf": {"59.0"}}
+	h.deriveValues(params)
+	relative, ok := paramFloat(params, "baromin")
+	if !ok {
+		t.Fatal("baromin not derived from absbaromin")
+	}
+	if math.Abs(relative-31.32) > 0.05 {
+		t.Errorf("baromin = %v, want about 31.32", relative)
+	}
+
+	// Station-provided relative pressure takes precedence
+	params = url.Values{"absbaromin": {"29.53"}, "baromin": {"30.01"}}
+	h.deriveValues(params)
+	if got := params.Get("baromin"); got != "30.01" {
+		t.Errorf("baromin = %q, want station value 30.01", got)
+	}
+}
+
+func TestHeatIndexF(t *testing.T) {
+	tests := []struct {
+		name     string
+		tempF    float64
+		humidity float64
+		expected float64
+	}{
+		{"mild uses simple formula", 70, 50, 69.4},
+		{"hot and humid", 90, 70, 105.9},
+		{"very hot and dry", 100, 10, 94.1},
+		{"warm and very humid", 85, 90, 101.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := HeatIndexF(tt.tempF, tt.humidity)
+			if math.Abs(result-tt.expected) > 0.5 {
+				t.Errorf("HeatIndexF(%v, %v) = %v, want %v", tt.tempF, tt.humidity, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWindChillF(t *testing.T) {
+	tests := []struct {
+		name     string
+		tempF    float64
+		windMph  float64
+		expected float64
+	}{
+		{"NWS table 0F 15mph", 0, 15, -19.4},
+		{"NWS table 30F 10mph", 30, 10, 21.2},
+		{"too warm returns temperature", 60, 20, 60},
+		{"calm returns temperature", 20, 2, 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := WindChillF(tt.tempF, tt.windMph)
+			if math.Abs(result-tt.expected) > 0.2 {
+				t.Errorf("WindChillF(%v, %v) = %v, want %v", tt.tempF, tt.windMph, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestApparentTemperatureF(t *testing.T) {
+	tests := []struct {
+		name     string
+		tempF    float64
+		humidity float64
+		windMph  float64
+		expected float64
+	}{
+		// 25 °C, 50 %, 0 m/s -> 26.2 °C
+		{"warm calm", 77, 50, 0, 79.2},
+		// 10 °C, 80 %, 5 m/s -> 5.7 °C
+		{"cool windy", 50, 80, 11.18, 42.3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ApparentTemperatureF(tt.tempF, tt.humidity, tt.windMph)
+			if math.Abs(result-tt.expected) > 0.5 {
+				t.Errorf("ApparentTemperatureF(%v, %v, %v) = %v, want %v",
+					tt.tempF, tt.humidity, tt.windMph, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDeriveValuesFeelsLike(t *testing.T) {
+	h := &WeatherHandler{cfg: &Config{Units: "metric"}}
+
+	params := url.Values{"tempf": {"30"}, "humidity": {"60"}, "windspeedmph": {"10"}}
+	h.deriveValues(params)
+	for _, param := range []string{"heatindexf", "windchillf", "apparenttempf"} {
+		if !params.Has(param) {
+			t.Errorf("%s not derived", param)
+		}
+	}
+
+	// Station-provided wind chill takes precedence
+	params = url.Values{"tempf": {"30"}, "windspeedmph": {"10"}, "windchillf": {"22.0"}}
+	h.deriveValues(params)
+	if got := params.Get("windchillf"); got != "22.0" {
+		t.Errorf("windchillf = %q, want station value 22.0", got)
+	}
+	if params.Has("heatindexf") || params.Has("apparenttempf") {
+		t.Error("heat index and apparent temperature need humidity")
+	}
+}
@@ -0,0 +1,138 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+// Copyright (C) 2025 Lenucksi
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// DiscoveryRegistry records the discovery config topics announced to a
+// broker, so configs of sensors that no longer exist (e.g. after the device
+// ID changed) can be removed on a later start. Topics are persisted as a JSON
+// list.
+type DiscoveryRegistry struct {
+	path   string
+	topics map[string]bool
+	mu     sync.Mutex
+}
+
+// NewDiscoveryRegistry creates a registry persisting to path, restoring
+// previously announced topics if present.
+func NewDiscoveryRegistry(path string) *DiscoveryRegistry {
+	r := &DiscoveryRegistry{path: path, topics: make(map[string]bool)}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		// First start, nothing announced yet
+	case err != nil:
+		slog.Warn("Failed to read announced discovery topics", "path", path, "error", err)
+	default:
+		var topics []string
+		if err := json.Unmarshal(data, &topics); err != nil {
+			slog.Warn("Failed to parse announced discovery topics", "path", path, "error", err)
+			break
+		}
+		for _, topic := range topics {
+			r.topics[topic] = true
+		}
+	}
+
+	return r
+}
+
+// Add records an announced topic.
+func (r *DiscoveryRegistry) Add(topic string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.topics[topic] {
+		return
+	}
+	r.topics[topic] = true
+	if err := r.save(); err != nil {
+		slog.Warn("Failed to save announced discovery topics", "path", r.path, "error", err)
+	}
+}
+
+// Remove forgets a topic whose config was cleared.
+func (r *DiscoveryRegistry) Remove(topic string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.topics[topic] {
+		return
+	}
+	delete(r.topics, topic)
+	if err := r.save(); err != nil {
+		slog.Warn("Failed to save announced discovery topics", "path", r.path, "error", err)
+	}
+}
+
+// Stale returns the recorded topics not in current, sorted.
+func (r *DiscoveryRegistry) Stale(current map[string]bool) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var stale []string
+	for topic := range r.topics {
+		if !current[topic] {
+			stale = append(stale, topic)
+		}
+	}
+	slices.Sort(stale)
+	return stale
+}
+
+// save writes the topics to disk atomically. The caller must hold mu.
+func (r *DiscoveryRegistry) save() error {
+	topics := make([]string, 0, len(r.topics))
+	for topic := range r.topics {
+		topics = append(topics, topic)
+	}
+	slices.Sort(topics)
+
+	data, err := json.Marshal(topics)
+	if err != nil {
+		return fmt.Errorf("failed to marshal discovery topics: %w", err)
+	}
+
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write discovery topics: %w", err)
+	}
+	if err := os.Rename(tmp, r.path); err != nil {
+		return fmt.Errorf("failed to replace discovery topics: %w", err)
+	}
+	return nil
+}
+
+// discoveryPath returns the path of a broker's announced discovery topics.
+func discoveryPath(cfg *Config, broker BrokerConfig) string {
+	if broker.Name == PrimaryBrokerName {
+		return filepath.Join(cfg.DataDir, "discovery.json")
+	}
+	return filepath.Join(cfg.DataDir, fmt.Sprintf("discovery-%s.json", broker.Name))
+}
@@ -0,0 +1,54 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+// Copyright (C) 2025 Lenucksi
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDiscoveryRegistry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "discovery.json")
+	r := NewDiscoveryRegistry(path)
+	r.Add("homeassistant/sensor/old_temperature/config")
+	r.Add("homeassistant/sensor/new_temperature/config")
+	r.Add("homeassistant/sensor/new_temperature/config")
+
+	// Topics survive a restart
+	restored := NewDiscoveryRegistry(path)
+	current := map[string]bool{"homeassistant/sensor/new_temperature/config": true}
+	want := []string{"homeassistant/sensor/old_temperature/config"}
+	if got := restored.Stale(current); !reflect.DeepEqual(got, want) {
+		t.Errorf("Stale() = %v, want %v", got, want)
+	}
+
+	restored.Remove("homeassistant/sensor/old_temperature/config")
+	if got := NewDiscoveryRegistry(path).Stale(current); len(got) != 0 {
+		t.Errorf("Stale() after Remove() = %v, want none", got)
+	}
+}
+
+func TestDiscoveryPath(t *testing.T) {
+	cfg := &Config{DataDir: "/data"}
+	if got := discoveryPath(cfg, cfg.PrimaryBroker()); got != "/data/discovery.json" {
+		t.Errorf("primary path = %q", got)
+	}
+	if got := discoveryPath(cfg, BrokerConfig{Name: "team"}); got != "/data/discovery-team.json" {
+		t.Errorf("extra broker path = %q", got)
+	}
+}
@@ -0,0 +1,102 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+// Copyright (C) 2025 Lenucksi
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// EcowittPath is the default path for the Ecowitt "customized server" upload.
+const EcowittPath = "/data/report/"
+
+// ecowittFields maps Ecowitt protocol field names to their Weather Underground
+// equivalents. Fields not listed here are ignored.
+var ecowittFields = map[string]string{
+	"dateutc":           "dateutc",
+	"stationtype":       "softwaretype",
+	"tempf":             "tempf",
+	"humidity":          "humidity",
+	"tempinf":           "indoortempf",
+	"humidityin":        "indoorhumidity",
+	"baromrelin":        "baromin",
+	"baromabsin":        "absbaromin",
+	"winddir":           "winddir",
+	"windspeedmph":      "windspeedmph",
+	"windgustmph":       "windgustmph",
+	"maxdailygust":      "maxdailygust",
+	"windspdmph_avg10m": "windspdmph_avg10m",
+	"winddir_avg10m":    "winddir_avg10m",
+	"rainratein":        "rainratein",
+	"eventrainin":       "eventrainin",
+	"hourlyrainin":      "rainin",
+	"dailyrainin":       "dailyrainin",
+	"24hourrainin":      "rain24hin",
+	"weeklyrainin":      "weeklyrainin",
+	"monthlyrainin":     "monthlyrainin",
+	"yearlyrainin":      "yearlyrainin",
+	"solarradiation":    "solarRadiation",
+	"uv":                "UV",
+}
+
+// Ecowitt numbers its extra sensor channels from 1.
+func init() {
+	for n := 1; n <= MaxChannels; n++ {
+		ecowittFields[fmt.Sprintf("temp%df", n)] = channelParam("temperature", n)
+		ecowittFields[fmt.Sprintf("humidity%d", n)] = channelParam("humidity", n)
+		ecowittFields[fmt.Sprintf("tf_ch%d", n)] = channelParam("soil_temperature", n)
+		ecowittFields[fmt.Sprintf("soilmoisture%d", n)] = channelParam("soil_moisture", n)
+		ecowittFields[fmt.Sprintf("leafwetness_ch%d", n)] = channelParam("leaf_wetness", n)
+	}
+}
+
+// EcowittHandler handles form-encoded POST uploads using the Ecowitt protocol.
+type EcowittHandler struct {
+	weather *WeatherHandler
+}
+
+// NewEcowittHandler creates a new Ecowitt handler publishing through weather.
+func NewEcowittHandler(weather *WeatherHandler) *EcowittHandler {
+	return &EcowittHandler{weather: weather}
+}
+
+// ServeHTTP handles the Ecowitt upload endpoint.
+func (h *EcowittHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		slog.Warn("Failed to parse Ecowitt upload", "error", err)
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	// PASSKEY identifies the station and is deliberately not logged
+	slog.Debug("Received Ecowitt upload", "path", r.URL.Path,
+		"stationtype", r.PostForm.Get("stationtype"), "model", r.PostForm.Get("model"))
+
+	queuedCount := h.weather.process(translateParams(r.PostForm, ecowittFields))
+	slog.Info("Processed Ecowitt update", "sensors_queued", queuedCount)
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	_, _ = fmt.Fprint(w, "success")
+}
@@ -0,0 +1,99 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+// Copyright (C) 2025 Lenucksi
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestTranslateParamsEcowitt(t *testing.T) {
+	form := url.Values{
+		"PASSKEY":        {"0123456789ABCDEF"},
+		"stationtype":    {"EasyWeatherPro_V5.1.6"},
+		"dateutc":        {"2025-12-01 11:15:31"},
+		"tempf":          {"45.3"},
+		"humidity":       {"81"},
+		"baromrelin":     {"29.921"},
+		"hourlyrainin":   {"0.020"},
+		"rainratein":     {"0.118"},
+		"eventrainin":    {"0.240"},
+		"solarradiation": {"12.34"},
+		"uv":             {"1"},
+	}
+
+	params := translateParams(form, ecowittFields)
+
+	expected := map[string]string{
+		"dateutc":        "2025-12-01 11:15:31",
+		"softwaretype":   "EasyWeatherPro_V5.1.6",
+		"tempf":          "45.3",
+		"humidity":       "81",
+		"baromin":        "29.921",
+		"rainin":         "0.020",
+		"rainratein":     "0.118",
+		"eventrainin":    "0.240",
+		"solarRadiation": "12.34",
+		"UV":             "1",
+	}
+
+	for param, want := range expected {
+		if got := params.Get(param); got != want {
+			t.Errorf("params[%q] = %q, want %q", param, got, want)
+		}
+	}
+
+	for _, field := range []string{"PASSKEY", "stationtype", "baromrelin", "hourlyrainin", "uv"} {
+		if params.Has(field) {
+			t.Errorf("params should not contain Ecowitt-only field %q", field)
+		}
+	}
+}
+
+func TestEcowittFieldsMapToKnownSensors(t *testing.T) {
+	for field, param := range ecowittFields {
+		if param == "dateutc" {
+			continue
+		}
+		if !knownQueryParam(param) {
+			t.Errorf("Ecowitt field %q maps to %q, which has no sensor definition", field, param)
+		}
+	}
+}
+
+func TestEcowittHandler(t *testing.T) {
+	form := url.Values{
+		"PASSKEY":     {"0123456789ABCDEF"},
+		"stationtype": {"EasyWeatherPro_V5.1.6"},
+		"dateutc":     {"2025-12-01 11:15:31"},
+		"tempf":       {"45.3"},
+	}
+	r := httptest.NewRequest(http.MethodPost, EcowittPath, strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	states := serveUpload(t, func(weather *WeatherHandler) http.Handler { return NewEcowittHandler(weather) }, r)
+
+	if got := states["station_software"]; got != "EasyWeatherPro_V5.1.6" {
+		t.Errorf("station_software = %q, want the Ecowitt stationtype", got)
+	}
+	if got := states["temperature"]; got != "7.4" {
+		t.Errorf("temperature = %q, want 7.4", got)
+	}
+}
@@ -21,7 +21,9 @@ import (
 	"log/slog"
 	"math"
 	"net/http"
+	"net/url"
 	"strconv"
+	"sync/atomic"
 	"time"
 )
 
@@ -48,17 +50,38 @@ func parseTimestamp(timestamp string) (time.Time, error) {
 
 // WeatherHandler handles incoming weather station data.
 type WeatherHandler struct {
-	cfg  *Config
-	mqtt *MQTTClient
-	wu   *WUForwarder
+	cfg       *Config
+	brokers   *Brokers
+	wu        *WUForwarder
+	history   *History
+	dailyGust DailyMax
+	rain      *RainAccumulator
+	watchdog  *StationWatchdog
+
+	// Values from the station that could not be parsed since the start
+	parseErrors atomic.Uint64
 }
 
 // NewWeatherHandler creates a new weather handler.
-func NewWeatherHandler(cfg *Config, mqtt *MQTTClient, wu *WUForwarder) *WeatherHandler {
-	return &WeatherHandler{
-		cfg:  cfg,
-		mqtt: mqtt,
-		wu:   wu,
+func NewWeatherHandler(cfg *Config, brokers *Brokers, wu *WUForwarder) *WeatherHandler {
+	h := &WeatherHandler{
+		cfg:     cfg,
+		brokers: brokers,
+		wu:      wu,
+		history: NewHistory(tendencyWindow + 15*time.Minute),
+		rain:    NewRainAccumulator(rainStatePath(cfg)),
+	}
+	if cfg.StationTimeout > 0 {
+		h.watchdog = NewStationWatchdog(cfg.StationTimeout, brokers.SetStationOnline)
+	}
+	return h
+}
+
+// Close stops the station watchdog, so no status is published to brokers
+// that are shutting down.
+func (h *WeatherHandler) Close() {
+	if h.watchdog != nil {
+		h.watchdog.Stop()
 	}
 }
 
@@ -69,9 +92,43 @@ func (h *WeatherHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Parse query parameters
 	query := r.URL.Query()
 
-	// Parse timestamp
+	queuedCount := h.process(query)
+	slog.Info("Processed weather update", "sensors_queued", queuedCount)
+
+	// Forward to Weather Underground if enabled
+	if h.cfg.WUForward && h.wu != nil {
+		go h.wu.Forward(r.URL.Query())
+	}
+
+	// Always return success to the weather station
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	_, _ = fmt.Fprint(w, "success")
+}
+
+// process converts a single station update and queues it for publishing. The
+// parameters use Weather Underground names; other protocols translate into
+// them first. Returns the number of sensors queued.
+func (h *WeatherHandler) process(params url.Values) int {
+	if h.watchdog != nil {
+		h.watchdog.Seen()
+	}
+
+	reading := h.reading(params)
+	if len(reading.Values) == 0 {
+		return 0
+	}
+	if !h.brokers.Enqueue(reading) {
+		return 0
+	}
+	return len(reading.Values)
+}
+
+// reading converts a station update into formatted sensor values.
+func (h *WeatherHandler) reading(params url.Values) Reading {
+	// Parse timestamp ("now" is a valid dateutc value in the WU protocol)
 	var measuredTime string
-	if dateutc := query.Get("dateutc"); dateutc != "" {
+	if dateutc := params.Get("dateutc"); dateutc != "" && dateutc != "now" {
 		// Parse timestamp with flexible format support (handles non-zero-padded dates)
 		parsedTime, err := parseTimestamp(dateutc)
 		if err == nil {
@@ -79,16 +136,39 @@ func (h *WeatherHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			measuredTime = localTime.Format(time.RFC3339)
 		} else {
 			slog.Warn("Failed to parse dateutc", "value", dateutc, "error", err)
+			h.parseErrors.Add(1)
 			measuredTime = time.Now().In(h.cfg.Timezone).Format(time.RFC3339)
 		}
 	} else {
 		measuredTime = time.Now().In(h.cfg.Timezone).Format(time.RFC3339)
 	}
 
+	// Add values computed by the bridge
+	now := time.Now()
+	h.deriveValues(params)
+	h.windAggregates(params, now)
+	h.rainTotals(params, now)
+
+	// Text states and extra attributes computed from the bridge's history
+	texts := make(map[string]string)
+	extraAttrs := make(map[string]map[string]interface{})
+	if delta, ok := h.pressureTendency(params, now); ok {
+		tendency := map[string]interface{}{"tendency_3h": h.pressureDelta(delta)}
+		texts["pressure_trend"] = PressureTrend(delta)
+		extraAttrs["barometric_pressure"] = tendency
+		extraAttrs["pressure_trend"] = tendency
+
+		if letter, forecast, ok := h.zambrettiForecast(params, delta, now); ok {
+			texts["forecast"] = forecast
+			extraAttrs["forecast"] = map[string]interface{}{"zambretti_code": letter}
+		}
+	}
+
+	reading := Reading{ReceivedAt: now}
+
 	// Process each sensor
-	publishedCount := 0
 	for _, sensor := range SensorDefinitions {
-		rawValue := query.Get(sensor.QueryParam)
+		rawValue := params.Get(sensor.QueryParam)
 		if rawValue == "" {
 			continue
 		}
@@ -97,6 +177,7 @@ func (h *WeatherHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		value, err := strconv.ParseFloat(rawValue, 64)
 		if err != nil {
 			slog.Warn("Failed to parse sensor value", "sensor", sensor.ID, "value", rawValue, "error", err)
+			h.parseErrors.Add(1)
 			continue
 		}
 
@@ -106,69 +187,138 @@ func (h *WeatherHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		// Format the value for publishing
 		stateValue := h.formatValue(&sensor, convertedValue)
 
-		// Publish sensor config
-		if err := h.mqtt.PublishSensorConfig(&sensor); err != nil {
-			slog.Error("Failed to publish sensor config", "sensor", sensor.ID, "error", err)
-			continue
+		// Build attributes
+		attrs := map[string]interface{}{
+			"measured_on": measuredTime,
 		}
 
-		// Publish sensor state
-		if err := h.mqtt.PublishSensorState(sensor.ID, stateValue); err != nil {
-			slog.Error("Failed to publish sensor state", "sensor", sensor.ID, "error", err)
+		// Add cardinal direction for wind direction sensors
+		switch sensor.ID {
+		case "wind_direction", "wind_direction_avg_2m", "wind_direction_avg_10m":
+			attrs["cardinal"] = DegreesToCardinal(value)
+		}
+
+		reading.add(sensor, stateValue, attrs, extraAttrs[sensor.ID])
+	}
+
+	// Process text sensors
+	for _, sensor := range TextSensorDefinitions {
+		stateValue, ok := texts[sensor.ID]
+		if !ok {
 			continue
 		}
 
-		// Build and publish attributes
 		attrs := map[string]interface{}{
 			"measured_on": measuredTime,
 		}
 
-		// Add cardinal direction for wind direction sensor
-		if sensor.ID == "wind_direction" {
-			attrs["cardinal"] = DegreesToCardinal(value)
+		reading.add(sensor, stateValue, attrs, extraAttrs[sensor.ID])
+	}
+
+	// Process binary sensors for weather conditions
+	conditions := h.conditions(params)
+	for _, sensor := range BinarySensorDefinitions {
+		stateValue, ok := conditions[sensor.ID]
+		if !ok {
+			continue
 		}
 
-		if err := h.mqtt.PublishSensorAttributes(sensor.ID, attrs); err != nil {
-			slog.Error("Failed to publish sensor attributes", "sensor", sensor.ID, "error", err)
+		attrs := map[string]interface{}{
+			"measured_on": measuredTime,
+		}
+
+		reading.add(sensor, stateValue, attrs, nil)
+	}
+
+	// Process diagnostic sensors (after the others, which count parse errors)
+	diagnostics := h.diagnostics(params, now)
+	for _, sensor := range DiagnosticSensorDefinitions {
+		stateValue, ok := diagnostics[sensor.ID]
+		if !ok {
 			continue
 		}
 
-		publishedCount++
-		slog.Debug("Published sensor data", "sensor", sensor.ID, "value", stateValue)
+		attrs := map[string]interface{}{
+			"measured_on": measuredTime,
+		}
+
+		reading.add(sensor, stateValue, attrs, nil)
 	}
 
-	slog.Info("Processed weather update", "sensors_published", publishedCount)
+	return reading
+}
 
-	// Forward to Weather Underground if enabled
-	if h.cfg.WUForward && h.wu != nil {
-		go h.wu.Forward(r.URL.Query())
+// diagnostics returns the states of the diagnostic sensors for an update
+// received at now.
+func (h *WeatherHandler) diagnostics(params url.Values, now time.Time) map[string]string {
+	h.history.Add("packets", now, 1)
+	packets := len(h.history.Since("packets", now.Add(-time.Hour)))
+
+	states := map[string]string{
+		"last_packet":      now.In(h.cfg.Timezone).Format(time.RFC3339),
+		"packets_per_hour": strconv.Itoa(packets),
+		"bridge_version":   Version,
+	}
+	if software := params.Get("softwaretype"); software != "" {
+		states["station_software"] = software
 	}
 
-	// Always return success to the weather station
-	w.Header().Set("Content-Type", "text/plain")
-	w.WriteHeader(http.StatusOK)
-	_, _ = fmt.Fprint(w, "success")
+	// The realtime update interval in seconds (WU "rtfreq")
+	if rtfreq := params.Get("rtfreq"); rtfreq != "" {
+		if interval, err := strconv.Atoi(rtfreq); err == nil && interval > 0 {
+			states["update_interval"] = strconv.Itoa(interval)
+		} else {
+			slog.Warn("Failed to parse realtime interval", "value", rtfreq)
+			h.parseErrors.Add(1)
+		}
+	}
+
+	states["parse_errors"] = strconv.FormatUint(h.parseErrors.Load(), 10)
+	return states
+}
+
+// add appends a sensor value to the reading, merging extra attributes into attrs.
+func (r *Reading) add(sensor SensorDefinition, stateValue string, attrs, extra map[string]interface{}) {
+	for k, v := range extra {
+		attrs[k] = v
+	}
+	r.Values = append(r.Values, SensorValue{Sensor: sensor, State: stateValue, Attributes: attrs})
+}
+
+// translateParams converts protocol-specific fields to Weather Underground
+// parameters using the given field mapping. Unmapped fields are dropped.
+func translateParams(values url.Values, fields map[string]string) url.Values {
+	params := url.Values{}
+	for field, v := range values {
+		param, ok := fields[field]
+		if !ok || len(v) == 0 {
+			continue
+		}
+		params.Set(param, v[0])
+	}
+	return params
 }
 
-// convertValue applies unit conversion based on sensor type and configured units.
+// convertValue applies unit conversion based on the sensor's imperial unit
+// (the unit the station reports in) and the configured units.
 func (h *WeatherHandler) convertValue(sensor *SensorDefinition, value float64) float64 {
 	if h.cfg.IsMetric() {
-		switch sensor.QueryParam {
-		case "tempf", "dewptf":
+		switch sensor.ImperialUnit {
+		case "°F":
 			return FToC(value)
-		case "baromin":
+		case "inHg":
 			return InHgToHPa(value)
-		case "windspeedmph", "windgustmph":
+		case "mph":
 			return MphToKmh(value)
-		case "rainin", "dailyrainin":
+		case "in", "in/h":
 			return InchToMm(value)
 		}
 	} else {
 		// Imperial: just round appropriately
-		switch sensor.QueryParam {
-		case "tempf", "dewptf", "baromin", "windspeedmph", "windgustmph":
+		switch sensor.ImperialUnit {
+		case "°F", "inHg", "mph":
 			return roundTo(value, 1)
-		case "rainin", "dailyrainin":
+		case "in", "in/h":
 			return roundTo(value, 2)
 		}
 	}
@@ -178,14 +328,14 @@ func (h *WeatherHandler) convertValue(sensor *SensorDefinition, value float64) f
 // formatValue formats the value as a string for MQTT publishing.
 func (h *WeatherHandler) formatValue(sensor *SensorDefinition, value float64) string {
 	// Handle special cases
-	switch sensor.QueryParam {
-	case "humidity", "UV", "winddir":
+	switch sensor.ImperialUnit {
+	case "%", "index", "°", "":
 		// These are integers or have no decimals
 		if math.IsNaN(value) {
 			return ""
 		}
 		return strconv.FormatFloat(value, 'f', -1, 64)
-	case "rainin", "dailyrainin":
+	case "in", "in/h":
 		if !h.cfg.IsMetric() {
 			// Imperial rain uses 2 decimal places
 			return strconv.FormatFloat(value, 'f', 2, 64)
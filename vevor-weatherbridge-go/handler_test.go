@@ -17,10 +17,41 @@
 package main
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
 	"time"
 )
 
+// serveUpload passes an upload to a station endpoint and returns the states
+// of the published reading by sensor ID.
+func serveUpload(t *testing.T, newHandler func(*WeatherHandler) http.Handler, r *http.Request) map[string]string {
+	t.Helper()
+
+	publisher := newFakePublisher()
+	close(publisher.release)
+	queue := NewPublishQueue(publisher, 10, DropOldest, time.Minute)
+	cfg := &Config{Units: "metric", Timezone: time.UTC, DataDir: t.TempDir()}
+	weather := NewWeatherHandler(cfg, &Brokers{queues: []*PublishQueue{queue}}, nil)
+
+	w := httptest.NewRecorder()
+	newHandler(weather).ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	queue.Close(time.Second)
+
+	if len(publisher.readings) != 1 {
+		t.Fatalf("published %d readings, want 1", len(publisher.readings))
+	}
+	states := make(map[string]string)
+	for _, value := range publisher.readings[0].Values {
+		states[value.Sensor.ID] = value.State
+	}
+	return states
+}
+
 func TestParseTimestamp(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -148,3 +179,80 @@ func FuzzParseTimestamp(f *testing.F) {
 		}
 	})
 }
+
+func TestConvertAndFormatValue(t *testing.T) {
+	metric := &WeatherHandler{cfg: &Config{Units: "metric"}}
+	imperial := &WeatherHandler{cfg: &Config{Units: "imperial"}}
+
+	tests := []struct {
+		param    string
+		value    float64
+		metric   string
+		imperial string
+	}{
+		{"tempf", 68.0, "20.0", "68.0"},
+		{"temp2f", 50.0, "10.0", "50.0"},
+		{"soiltempf", 41.0, "5.0", "41.0"},
+		{"baromin", 29.92, "1013.2", "29.9"},
+		{"windspeedmph", 10.0, "16.1", "10.0"},
+		{"dailyrainin", 0.5, "12.7", "0.50"},
+		{"rainratein", 0.118, "3.0", "0.12"},
+		{"humidity", 55, "55", "55"},
+		{"soilmoisture2", 31, "31", "31"},
+		{"winddir", 270, "270", "270"},
+		{"solarRadiation", 123.45, "123.5", "123.5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.param, func(t *testing.T) {
+			sensor := GetSensorByQueryParam(tt.param)
+			if sensor == nil {
+				t.Fatalf("no sensor definition for %q", tt.param)
+			}
+			if got := metric.formatValue(sensor, metric.convertValue(sensor, tt.value)); got != tt.metric {
+				t.Errorf("metric = %q, want %q", got, tt.metric)
+			}
+			if got := imperial.formatValue(sensor, imperial.convertValue(sensor, tt.value)); got != tt.imperial {
+				t.Errorf("imperial = %q, want %q", got, tt.imperial)
+			}
+		})
+	}
+}
+
+func TestDiagnosticSensors(t *testing.T) {
+	h := &WeatherHandler{
+		cfg:     &Config{Units: "metric", Timezone: time.UTC},
+		history: NewHistory(2 * time.Hour),
+	}
+
+	params := url.Values{
+		"tempf":        {"invalid"},
+		"softwaretype": {"EasyWeatherV1.6.4"},
+		"rtfreq":       {"5"},
+	}
+	h.reading(params)
+	reading := h.reading(params)
+
+	states := make(map[string]string)
+	for _, value := range reading.Values {
+		if value.Sensor.EntityCategory == EntityCategoryDiagnostic {
+			states[value.Sensor.ID] = value.State
+		}
+	}
+
+	want := map[string]string{
+		"packets_per_hour": "2",
+		"parse_errors":     "2",
+		"station_software": "EasyWeatherV1.6.4",
+		"update_interval":  "5",
+		"bridge_version":   Version,
+	}
+	for id, state := range want {
+		if states[id] != state {
+			t.Errorf("%s = %q, want %q", id, states[id], state)
+		}
+	}
+	if _, err := time.Parse(time.RFC3339, states["last_packet"]); err != nil {
+		t.Errorf("last_packet = %q, want an RFC 3339 timestamp", states["last_packet"])
+	}
+}
@@ -0,0 +1,95 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+// Copyright (C) 2025 Lenucksi
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// Sample is a single timestamped sensor value.
+type Sample struct {
+	At    time.Time `json:"at"`
+	Value float64   `json:"value"`
+}
+
+// History keeps recent samples per sensor ID in memory. Samples older than the
+// retention period are discarded as new ones are added.
+type History struct {
+	retention time.Duration
+	samples   map[string][]Sample
+	mu        sync.Mutex
+}
+
+// NewHistory creates a new history keeping samples for the given duration.
+func NewHistory(retention time.Duration) *History {
+	return &History{
+		retention: retention,
+		samples:   make(map[string][]Sample),
+	}
+}
+
+// Add records a sample for a sensor and prunes samples past the retention period.
+func (h *History) Add(sensorID string, at time.Time, value float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	samples := append(h.samples[sensorID], Sample{At: at, Value: value})
+
+	cutoff := at.Add(-h.retention)
+	first := 0
+	for first < len(samples) && samples[first].At.Before(cutoff) {
+		first++
+	}
+	h.samples[sensorID] = samples[first:]
+}
+
+// Since returns a copy of the samples for a sensor recorded at or after since,
+// oldest first.
+func (h *History) Since(sensorID string, since time.Time) []Sample {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var result []Sample
+	for _, s := range h.samples[sensorID] {
+		if !s.At.Before(since) {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// DailyMax tracks the maximum value since local midnight.
+type DailyMax struct {
+	day   string
+	value float64
+	mu    sync.Mutex
+}
+
+// Add records a value at the given local time and returns the maximum for
+// that day. The maximum resets when the date changes.
+func (d *DailyMax) Add(at time.Time, value float64) float64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	day := at.Format(time.DateOnly)
+	if day != d.day || value > d.value {
+		d.day = day
+		d.value = value
+	}
+	return d.value
+}
@@ -0,0 +1,79 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+// Copyright (C) 2025 Lenucksi
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistoryPrunesOldSamples(t *testing.T) {
+	h := NewHistory(time.Hour)
+	start := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+
+	h.Add("temperature", start, 1)
+	h.Add("temperature", start.Add(30*time.Minute), 2)
+	h.Add("temperature", start.Add(90*time.Minute), 3)
+
+	samples := h.Since("temperature", time.Time{})
+	if len(samples) != 2 {
+		t.Fatalf("len(samples) = %d, want 2", len(samples))
+	}
+	if samples[0].Value != 2 || samples[1].Value != 3 {
+		t.Errorf("samples = %v, want values 2 and 3", samples)
+	}
+}
+
+func TestHistorySince(t *testing.T) {
+	h := NewHistory(3 * time.Hour)
+	start := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 4; i++ {
+		h.Add("barometric_pressure", start.Add(time.Duration(i)*time.Hour), float64(1000+i))
+	}
+	h.Add("temperature", start, 20)
+
+	samples := h.Since("barometric_pressure", start.Add(2*time.Hour))
+	if len(samples) != 2 {
+		t.Fatalf("len(samples) = %d, want 2", len(samples))
+	}
+	if samples[0].Value != 1002 {
+		t.Errorf("oldest sample = %v, want 1002", samples[0].Value)
+	}
+
+	if samples := h.Since("unknown", time.Time{}); len(samples) != 0 {
+		t.Errorf("unknown sensor returned %d samples, want 0", len(samples))
+	}
+}
+
+func TestDailyMax(t *testing.T) {
+	var d DailyMax
+	day := time.Date(2025, 12, 1, 8, 0, 0, 0, time.UTC)
+
+	if got := d.Add(day, 10); got != 10 {
+		t.Errorf("first value = %v, want 10", got)
+	}
+	if got := d.Add(day.Add(time.Hour), 7); got != 10 {
+		t.Errorf("lower value = %v, want 10", got)
+	}
+	if got := d.Add(day.Add(2*time.Hour), 15); got != 15 {
+		t.Errorf("higher value = %v, want 15", got)
+	}
+	if got := d.Add(day.Add(24*time.Hour), 3); got != 3 {
+		t.Errorf("next day = %v, want 3", got)
+	}
+}
@@ -18,10 +18,12 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 )
@@ -44,14 +46,14 @@ func main() {
 		"timezone", cfg.Timezone.String(),
 	)
 
-	// Connect to MQTT broker
-	slog.Info("Connecting to MQTT broker", "host", cfg.MQTTHost, "port", cfg.MQTTPort)
-	mqttClient, err := NewMQTTClient(cfg)
+	// Connect to the MQTT brokers. Each publishes asynchronously through its
+	// own queue, so a slow broker never delays the station or other brokers.
+	brokers, err := ConnectBrokers(cfg)
 	if err != nil {
 		slog.Error("Failed to connect to MQTT broker", "error", err)
 		os.Exit(1)
 	}
-	defer mqttClient.Close()
+	defer brokers.Close()
 
 	// Create Weather Underground forwarder if enabled
 	var wuForwarder *WUForwarder
@@ -61,20 +63,55 @@ func main() {
 	}
 
 	// Create HTTP handler
-	handler := NewWeatherHandler(cfg, mqttClient, wuForwarder)
+	handler := NewWeatherHandler(cfg, brokers, wuForwarder)
 
 	// Setup HTTP server
 	mux := http.NewServeMux()
 	mux.Handle("/weatherstation/updateweatherstation.php", handler)
 
-	// Health check endpoint
+	// Ecowitt "customized server" uploads (registered with and without trailing slash,
+	// since a redirect would drop the POST body)
+	ecowittHandler := NewEcowittHandler(handler)
+	mux.Handle(EcowittPath, ecowittHandler)
+	mux.Handle(strings.TrimSuffix(EcowittPath, "/"), ecowittHandler)
+
+	// Ambient Weather "custom server" uploads
+	mux.Handle(AmbientPath, NewAmbientHandler(handler))
+
+	// Health check endpoint, healthy while the primary broker is connected.
+	// Extra brokers are listed but do not affect the status.
 	mux.HandleFunc("/health", func(w http.ResponseWriter, _ *http.Request) {
-		if mqttClient.IsConnected() {
-			w.WriteHeader(http.StatusOK)
-			_, _ = w.Write([]byte("OK"))
-		} else {
-			w.WriteHeader(http.StatusServiceUnavailable)
-			_, _ = w.Write([]byte("MQTT disconnected"))
+		statuses := brokers.Status()
+		code, summary := http.StatusOK, "OK"
+		if len(statuses) == 0 || !statuses[0].Connected {
+			code, summary = http.StatusServiceUnavailable, "MQTT disconnected"
+		}
+
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(code)
+		_, _ = fmt.Fprintln(w, summary)
+		for _, status := range statuses {
+			state := "connected"
+			if !status.Connected {
+				state = "disconnected"
+			}
+			_, _ = fmt.Fprintf(w, "%s: %s\n", status.Name, state)
+		}
+	})
+
+	// Broker and publish queue metrics in Prometheus text format
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		for _, status := range brokers.Status() {
+			connected := 0
+			if status.Connected {
+				connected = 1
+			}
+			_, _ = fmt.Fprintf(w, "weatherbridge_broker_connected{broker=%q} %d\n", status.Name, connected)
+			_, _ = fmt.Fprintf(w, "weatherbridge_queue_length{broker=%q} %d\n", status.Name, status.Queue.Length)
+			_, _ = fmt.Fprintf(w, "weatherbridge_readings_published_total{broker=%q} %d\n", status.Name, status.Queue.Published)
+			_, _ = fmt.Fprintf(w, "weatherbridge_readings_dropped_total{broker=%q} %d\n", status.Name, status.Queue.Dropped)
+			_, _ = fmt.Fprintf(w, "weatherbridge_readings_late_total{broker=%q} %d\n", status.Name, status.Queue.Late)
 		}
 	})
 
@@ -109,6 +146,7 @@ func main() {
 	if err := server.Shutdown(ctx); err != nil {
 		slog.Error("Error during server shutdown", "error", err)
 	}
+	handler.Close()
 
 	slog.Info("Server stopped")
 }
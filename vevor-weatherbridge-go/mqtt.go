@@ -17,9 +17,17 @@
 package main
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"maps"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -36,26 +44,41 @@ const (
 
 // DiscoveryPayload represents a Home Assistant MQTT Discovery config message.
 type DiscoveryPayload struct {
-	Name                      string     `json:"name"`
-	StateTopic                string     `json:"state_topic"`
-	UniqueID                  string     `json:"unique_id"`
-	DeviceClass               string     `json:"device_class,omitempty"`
-	UnitOfMeasurement         string     `json:"unit_of_measurement,omitempty"`
-	StateClass                string     `json:"state_class,omitempty"`
-	Icon                      string     `json:"icon,omitempty"`
-	SuggestedDisplayPrecision int        `json:"suggested_display_precision,omitempty"`
-	Device                    DeviceInfo `json:"device"`
-	AvailabilityTopic         string     `json:"availability_topic"`
-	JSONAttributesTopic       string     `json:"json_attributes_topic,omitempty"`
-	Origin                    OriginInfo `json:"origin,omitempty"`
+	Name                      string         `json:"name"`
+	StateTopic                string         `json:"state_topic"`
+	UniqueID                  string         `json:"unique_id"`
+	DeviceClass               string         `json:"device_class,omitempty"`
+	UnitOfMeasurement         string         `json:"unit_of_measurement,omitempty"`
+	StateClass                string         `json:"state_class,omitempty"`
+	Icon                      string         `json:"icon,omitempty"`
+	SuggestedDisplayPrecision int            `json:"suggested_display_precision,omitempty"`
+	EntityCategory            string         `json:"entity_category,omitempty"`
+	Device                    DeviceInfo     `json:"device"`
+	AvailabilityTopic         string         `json:"availability_topic,omitempty"`
+	Availability              []Availability `json:"availability,omitempty"`
+	AvailabilityMode          string         `json:"availability_mode,omitempty"`
+	ExpireAfter               int            `json:"expire_after,omitempty"`
+	JSONAttributesTopic       string         `json:"json_attributes_topic,omitempty"`
+	ValueTemplate             string         `json:"value_template,omitempty"`
+	JSONAttributesTemplate    string         `json:"json_attributes_template,omitempty"`
+	Origin                    OriginInfo     `json:"origin,omitempty"`
+}
+
+// Availability represents one of several availability topics of an entity.
+type Availability struct {
+	Topic string `json:"topic"`
 }
 
 // DeviceInfo represents device information for Home Assistant.
 type DeviceInfo struct {
-	Identifiers  []string `json:"identifiers"`
-	Name         string   `json:"name"`
-	Manufacturer string   `json:"manufacturer"`
-	Model        string   `json:"model"`
+	Identifiers      []string `json:"identifiers"`
+	Name             string   `json:"name"`
+	Manufacturer     string   `json:"manufacturer"`
+	Model            string   `json:"model"`
+	SWVersion        string   `json:"sw_version,omitempty"`
+	ConfigurationURL string   `json:"configuration_url,omitempty"`
+	SuggestedArea    string   `json:"suggested_area,omitempty"`
+	ViaDevice        string   `json:"via_device,omitempty"`
 }
 
 // OriginInfo represents the origin of the integration.
@@ -69,49 +92,198 @@ type OriginInfo struct {
 type MQTTClient struct {
 	client    mqtt.Client
 	cfg       *Config
+	broker    BrokerConfig
+	brokerURL string
+	messages  map[string]messageSettings
 	connected bool
+	station   bool   // last known station status, see StationWatchdog
+	firmware  string // station firmware reported as softwaretype
 	mu        sync.RWMutex
+
+	// Discovery configs announced on the current connection, by sensor ID.
+	// Sensors stay known across reconnects so they can be re-announced.
+	announced   map[string]string
+	known       map[string]SensorDefinition
+	discoveryMu sync.Mutex
+
+	// Discovery topics announced to the broker across restarts (nil if not
+	// persisted)
+	registry *DiscoveryRegistry
+
+	// Last published state and attributes by topic, re-published when Home
+	// Assistant comes back online
+	lastState map[string]lastMessage
+	lastAttrs map[string]lastMessage
+	stateMu   sync.Mutex
+
+	// Readings kept on disk while the broker is unreachable (nil if disabled).
+	// publishMu keeps live and replayed readings in order.
+	buffer    *OfflineBuffer
+	publishMu sync.Mutex
 }
 
-// NewMQTTClient creates and connects a new MQTT client.
-func NewMQTTClient(cfg *Config) (*MQTTClient, error) {
-	m := &MQTTClient{cfg: cfg}
+// NewMQTTClient creates and connects a new MQTT client for a broker.
+func NewMQTTClient(cfg *Config, broker BrokerConfig) (*MQTTClient, error) {
+	m := newMQTTClient(cfg, broker)
+	if cfg.MQTTBufferSize > 0 {
+		m.buffer = NewOfflineBuffer(bufferPath(cfg, broker), cfg.MQTTBufferSize)
+	}
+	m.registry = NewDiscoveryRegistry(discoveryPath(cfg, broker))
+
+	u, err := brokerURL(&broker)
+	if err != nil {
+		return nil, err
+	}
+	m.brokerURL = u.Redacted()
+
+	var tlsConfig *tls.Config
+	if broker.TLS || u.Scheme == "ssl" || u.Scheme == "wss" {
+		tlsConfig, err = newTLSConfig(&broker)
+		if err != nil {
+			return nil, err
+		}
+	}
+	slog.Info("Connecting to MQTT broker", "broker", broker.Name, "url", m.brokerURL, "version", broker.Version)
+
+	clientID := fmt.Sprintf("vevor-weatherbridge-%s", cfg.DeviceID)
+	if broker.Name != PrimaryBrokerName {
+		clientID += "-" + broker.Name
+	}
+
+	if broker.Version == MQTTVersion5 {
+		m.client = newMQTT5Client(m, u, tlsConfig, clientID)
+	} else {
+		m.client = newMQTT3Client(m, u, tlsConfig, clientID)
+	}
+
+	// Connect
+	token := m.client.Connect()
+	if token.WaitTimeout(10 * time.Second) {
+		if token.Error() != nil {
+			return nil, fmt.Errorf("MQTT connection failed: %w", token.Error())
+		}
+	} else {
+		slog.Warn("MQTT connection timeout, will retry in background")
+	}
 
+	return m, nil
+}
+
+// newMQTT3Client creates a paho MQTT 3.1.1 client for the broker of m.
+func newMQTT3Client(m *MQTTClient, u *url.URL, tlsConfig *tls.Config, clientID string) mqtt.Client {
 	opts := mqtt.NewClientOptions()
-	opts.AddBroker(fmt.Sprintf("tcp://%s:%d", cfg.MQTTHost, cfg.MQTTPort))
-	opts.SetClientID(fmt.Sprintf("vevor-weatherbridge-%s", cfg.DeviceID))
+	if tlsConfig != nil {
+		opts.SetTLSConfig(tlsConfig)
+	}
+	opts.AddBroker(u.String())
+	opts.SetClientID(clientID)
 	opts.SetKeepAlive(60 * time.Second)
 	opts.SetAutoReconnect(true)
 	opts.SetConnectRetry(true)
 	opts.SetConnectRetryInterval(10 * time.Second)
 
 	// Set credentials if provided
-	if cfg.MQTTUser != "" {
-		opts.SetUsername(cfg.MQTTUser)
-		opts.SetPassword(cfg.MQTTPassword)
+	if m.broker.User != "" {
+		opts.SetUsername(m.broker.User)
+		opts.SetPassword(m.broker.Password)
 	}
 
 	// Set Last Will and Testament
-	availabilityTopic := m.AvailabilityTopic()
-	opts.SetWill(availabilityTopic, "offline", 1, true)
+	availability := m.messages[KindAvailability]
+	opts.SetWill(m.AvailabilityTopic(), "offline", availability.qos, availability.retain)
 
 	// Set callbacks
 	opts.SetOnConnectHandler(m.onConnect)
 	opts.SetConnectionLostHandler(m.onConnectionLost)
 
-	m.client = mqtt.NewClient(opts)
+	return mqtt.NewClient(opts)
+}
+
+// brokerURL returns the broker URL, either as configured or built from host
+// and port. Supported schemes are tcp, ssl, ws and wss; WebSocket URLs may
+// include a path (e.g. wss://example.com/mqtt).
+func brokerURL(broker *BrokerConfig) (*url.URL, error) {
+	if broker.URL == "" {
+		scheme := "tcp"
+		if broker.TLS {
+			scheme = "ssl"
+		}
+		return &url.URL{Scheme: scheme, Host: net.JoinHostPort(broker.Host, strconv.Itoa(broker.Port))}, nil
+	}
 
-	// Connect
-	token := m.client.Connect()
-	if token.WaitTimeout(10 * time.Second) {
-		if token.Error() != nil {
-			return nil, fmt.Errorf("MQTT connection failed: %w", token.Error())
+	u, err := url.Parse(broker.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MQTT URL: %w", err)
+	}
+	switch u.Scheme {
+	case "tcp", "ssl", "ws", "wss":
+	default:
+		return nil, fmt.Errorf("unsupported MQTT URL scheme %q (use tcp, ssl, ws or wss)", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("MQTT URL %q has no host", u.Redacted())
+	}
+
+	// paho only uses TLS for ssl and wss, never send credentials in plaintext
+	// when TLS is enabled
+	if broker.TLS {
+		switch u.Scheme {
+		case "tcp":
+			u.Scheme = "ssl"
+		case "ws":
+			u.Scheme = "wss"
 		}
-	} else {
-		slog.Warn("MQTT connection timeout, will retry in background")
 	}
+	return u, nil
+}
 
-	return m, nil
+// newTLSConfig builds the TLS configuration for the broker connection. The
+// system roots are used unless a CA file is configured.
+func newTLSConfig(broker *BrokerConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         broker.ServerName,
+		InsecureSkipVerify: broker.TLSInsecure,
+	}
+
+	if broker.CAFile != "" {
+		pem, err := os.ReadFile(broker.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read MQTT CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in MQTT CA file %s", broker.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if broker.ClientCert != "" || broker.ClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(broker.ClientCert, broker.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load MQTT client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if broker.TLSInsecure {
+		slog.Warn("MQTT TLS certificate verification is disabled", "broker", broker.Name)
+	}
+
+	return tlsConfig, nil
+}
+
+// newMQTTClient creates an MQTT client wrapper without a paho client.
+func newMQTTClient(cfg *Config, broker BrokerConfig) *MQTTClient {
+	return &MQTTClient{
+		cfg:       cfg,
+		broker:    broker,
+		messages:  newMessageSettings(&broker),
+		announced: make(map[string]string),
+		known:     make(map[string]SensorDefinition),
+		lastState: make(map[string]lastMessage),
+		lastAttrs: make(map[string]lastMessage),
+	}
 }
 
 // onConnect is called when the client connects to the broker.
@@ -120,17 +292,134 @@ func (m *MQTTClient) onConnect(client mqtt.Client) {
 	m.connected = true
 	m.mu.Unlock()
 
-	slog.Info("MQTT connected", "host", m.cfg.MQTTHost, "port", m.cfg.MQTTPort)
+	slog.Info("MQTT connected", "broker", m.broker.Name, "url", m.brokerURL)
 
 	// Publish online status
+	m.publishAvailability(client)
+
+	// Remove configs of sensors that no longer exist
+	m.clearStaleDiscovery(client)
+
+	if m.discovery() {
+		// Watch for Home Assistant restarts (birth message)
+		statusTopic := m.StatusTopic()
+		token := client.Subscribe(statusTopic, m.qos(), m.onHAStatus)
+		token.Wait()
+		if token.Error() != nil {
+			slog.Error("Failed to subscribe to Home Assistant status", "topic", statusTopic, "error", token.Error())
+		} else {
+			slog.Debug("Subscribed to Home Assistant status", "topic", statusTopic)
+		}
+
+		// Discovery configs are announced once per connection
+		m.announceAll()
+	}
+
+	// Publish readings buffered while disconnected
+	go m.replayBuffer()
+}
+
+// clearStaleDiscovery publishes empty retained payloads to discovery topics
+// announced earlier that do not belong to a current sensor, e.g. after the
+// device ID changed, so Home Assistant removes the entities.
+func (m *MQTTClient) clearStaleDiscovery(client mqtt.Client) {
+	if m.registry == nil {
+		return
+	}
+
+	for _, topic := range m.registry.Stale(m.configTopics()) {
+		token := client.Publish(topic, m.messages[KindConfig].qos, true, "")
+		token.Wait()
+		if token.Error() != nil {
+			slog.Error("Failed to remove stale sensor config", "topic", topic, "error", token.Error())
+			continue
+		}
+		m.registry.Remove(topic)
+		slog.Info("Removed stale sensor config", "topic", topic)
+	}
+}
+
+// configTopics returns the discovery topics of all current sensors. Brokers
+// without discovery topics have none.
+func (m *MQTTClient) configTopics() map[string]bool {
+	topics := make(map[string]bool)
+	if !m.discovery() {
+		return topics
+	}
+	for _, definitions := range [][]SensorDefinition{SensorDefinitions, TextSensorDefinitions, DiagnosticSensorDefinitions, BinarySensorDefinitions} {
+		for i := range definitions {
+			topics[m.ConfigTopic(&definitions[i])] = true
+		}
+	}
+	return topics
+}
+
+// onHAStatus is called when Home Assistant publishes its status. On the
+// "online" birth message, discovery configs, availability and the last known
+// states are re-published so entities recover after a Home Assistant restart.
+func (m *MQTTClient) onHAStatus(client mqtt.Client, msg mqtt.Message) {
+	if string(msg.Payload()) != "online" {
+		slog.Debug("Home Assistant status", "status", string(msg.Payload()))
+		return
+	}
+
+	slog.Info("Home Assistant came online, re-announcing sensors")
+
+	// Message handlers must not block the paho client
+	go func() {
+		m.publishAvailability(client)
+		m.announceAll()
+		m.republishStates()
+	}()
+}
+
+// publishAvailability publishes the online status to the availability topic
+// and, if the station watchdog is enabled, the station status.
+func (m *MQTTClient) publishAvailability(client mqtt.Client) {
 	availTopic := m.AvailabilityTopic()
-	token := client.Publish(availTopic, 1, true, "online")
+	token := m.publishKind(client, KindAvailability, availTopic, "online")
 	token.Wait()
 	if token.Error() != nil {
 		slog.Error("Failed to publish availability status", "topic", availTopic, "error", token.Error())
 	} else {
 		slog.Debug("Published availability status", "topic", availTopic, "status", "online")
 	}
+
+	if m.cfg.StationTimeout > 0 {
+		m.mu.RLock()
+		online := m.station
+		m.mu.RUnlock()
+		m.publishStationStatus(client, online)
+	}
+}
+
+// SetStationOnline records the station status and publishes it if connected.
+func (m *MQTTClient) SetStationOnline(online bool) {
+	m.mu.Lock()
+	m.station = online
+	connected := m.connected
+	m.mu.Unlock()
+
+	if connected {
+		m.publishStationStatus(m.client, online)
+	}
+}
+
+// publishStationStatus publishes the station status to the station topic.
+func (m *MQTTClient) publishStationStatus(client mqtt.Client, online bool) {
+	status := "offline"
+	if online {
+		status = "online"
+	}
+
+	topic := m.StationTopic()
+	token := m.publishKind(client, KindStation, topic, status)
+	token.Wait()
+	if token.Error() != nil {
+		slog.Error("Failed to publish station status", "topic", topic, "error", token.Error())
+	} else {
+		slog.Debug("Published station status", "topic", topic, "status", status)
+	}
 }
 
 // onConnectionLost is called when the connection is lost.
@@ -139,7 +428,7 @@ func (m *MQTTClient) onConnectionLost(client mqtt.Client, err error) {
 	m.connected = false
 	m.mu.Unlock()
 
-	slog.Warn("MQTT connection lost", "error", err)
+	slog.Warn("MQTT connection lost", "broker", m.broker.Name, "error", err)
 }
 
 // IsConnected returns true if the client is connected.
@@ -149,41 +438,131 @@ func (m *MQTTClient) IsConnected() bool {
 	return m.connected
 }
 
+// discovery reports whether the broker uses Home Assistant discovery topics.
+func (m *MQTTClient) discovery() bool {
+	return m.broker.TopicStyle != TopicStyleFlat
+}
+
+// jsonState reports whether the broker gets one JSON state document per
+// update instead of a state and attributes topic per sensor.
+func (m *MQTTClient) jsonState() bool {
+	return m.broker.StateMode == StateModeJSON
+}
+
+// qos returns the QoS level for subscriptions to this broker.
+func (m *MQTTClient) qos() byte {
+	return byte(m.broker.QoS)
+}
+
+// publishKind publishes a payload with the QoS and retain flag of its kind.
+func (m *MQTTClient) publishKind(client mqtt.Client, kind, topic string, payload interface{}) mqtt.Token {
+	s := m.messages[kind]
+	return client.Publish(topic, s.qos, s.retain, payload)
+}
+
+// publishKindWithProperties publishes a payload like publishKind, attaching
+// MQTT 5 properties if the client supports them.
+func (m *MQTTClient) publishKindWithProperties(client mqtt.Client, kind, topic string, payload interface{}, props publishProperties) mqtt.Token {
+	s := m.messages[kind]
+	if p, ok := client.(propertyPublisher); ok {
+		return p.PublishWithProperties(topic, s.qos, s.retain, payload, props)
+	}
+	return client.Publish(topic, s.qos, s.retain, payload)
+}
+
+// lastMessage is the last state or attributes message published to a topic.
+type lastMessage struct {
+	payload interface{}
+	station bool // station data, see stateProperties
+}
+
+// stateProperties returns the MQTT 5 properties of state and attributes
+// messages. Station data expires after the configured message expiry or, by
+// default, the station timeout, so retained values do not outlive the
+// station. Diagnostics do not expire.
+func (m *MQTTClient) stateProperties(station bool, measuredOn string) publishProperties {
+	props := publishProperties{
+		User: map[string]string{"station_id": m.cfg.DeviceID},
+	}
+	if station {
+		props.MessageExpiry = m.cfg.MQTTMessageExpiry
+		if props.MessageExpiry == 0 {
+			props.MessageExpiry = m.cfg.StationTimeout
+		}
+	}
+	if measuredOn != "" {
+		props.User["measured_on"] = measuredOn
+	}
+	return props
+}
+
+// measuredOn returns the measured_on attribute of a sensor value, if any.
+func measuredOn(attrs map[string]interface{}) string {
+	value, _ := attrs["measured_on"].(string)
+	return value
+}
+
+// topic renders the topic template of a message kind for a sensor, or for
+// the whole device if sensor is nil.
+func (m *MQTTClient) topic(kind string, sensor *SensorDefinition) string {
+	var topic strings.Builder
+	data := TopicData{
+		Prefix:    m.broker.Prefix,
+		DeviceID:  m.cfg.DeviceID,
+		Component: ComponentSensor,
+	}
+	if sensor != nil {
+		data.SensorID = sensor.ID
+		data.Component = sensor.component()
+	}
+	if err := m.messages[kind].topic.Execute(&topic, data); err != nil {
+		slog.Error("Failed to render MQTT topic", "kind", kind, "error", err)
+	}
+	return topic.String()
+}
+
+// StatusTopic returns the topic Home Assistant publishes its birth and last
+// will messages to.
+func (m *MQTTClient) StatusTopic() string {
+	return fmt.Sprintf("%s/status", m.broker.Prefix)
+}
+
 // AvailabilityTopic returns the availability topic for this device.
 func (m *MQTTClient) AvailabilityTopic() string {
-	return fmt.Sprintf("%s/sensor/%s/availability", m.cfg.MQTTPrefix, m.cfg.DeviceID)
+	return m.topic(KindAvailability, nil)
+}
+
+// StationTopic returns the topic reporting whether the station sends updates.
+func (m *MQTTClient) StationTopic() string {
+	return m.topic(KindStation, nil)
 }
 
 // ConfigTopic returns the config topic for a sensor.
-func (m *MQTTClient) ConfigTopic(sensorID string) string {
-	return fmt.Sprintf("%s/sensor/%s_%s/config", m.cfg.MQTTPrefix, m.cfg.DeviceID, sensorID)
+func (m *MQTTClient) ConfigTopic(sensor *SensorDefinition) string {
+	return m.topic(KindConfig, sensor)
 }
 
-// StateTopic returns the state topic for a sensor.
-func (m *MQTTClient) StateTopic(sensorID string) string {
-	return fmt.Sprintf("%s/sensor/%s_%s/state", m.cfg.MQTTPrefix, m.cfg.DeviceID, sensorID)
+// StateTopic returns the state topic for a sensor, or the state document
+// topic in JSON state mode if sensor is nil.
+func (m *MQTTClient) StateTopic(sensor *SensorDefinition) string {
+	return m.topic(KindState, sensor)
 }
 
 // AttributesTopic returns the attributes topic for a sensor.
-func (m *MQTTClient) AttributesTopic(sensorID string) string {
-	return fmt.Sprintf("%s/sensor/%s_%s/attributes", m.cfg.MQTTPrefix, m.cfg.DeviceID, sensorID)
+func (m *MQTTClient) AttributesTopic(sensor *SensorDefinition) string {
+	return m.topic(KindAttributes, sensor)
 }
 
-// PublishSensorConfig publishes the discovery config for a sensor.
-func (m *MQTTClient) PublishSensorConfig(sensor *SensorDefinition) error {
+// discoveryPayload builds the discovery config payload for a sensor.
+func (m *MQTTClient) discoveryPayload(sensor *SensorDefinition) ([]byte, error) {
 	payload := DiscoveryPayload{
-		Name:                fmt.Sprintf("%s %s", m.cfg.DeviceName, sensor.Name),
-		StateTopic:          m.StateTopic(sensor.ID),
+		Name:                fmt.Sprintf("%s %s", m.cfg.DeviceName, sensor.DisplayName(m.cfg.ChannelNames)),
+		StateTopic:          m.StateTopic(sensor),
 		UniqueID:            fmt.Sprintf("%s_%s", m.cfg.DeviceID, sensor.ID),
 		UnitOfMeasurement:   sensor.GetUnit(m.cfg.IsMetric()),
 		AvailabilityTopic:   m.AvailabilityTopic(),
-		JSONAttributesTopic: m.AttributesTopic(sensor.ID),
-		Device: DeviceInfo{
-			Identifiers:  []string{m.cfg.DeviceID},
-			Name:         m.cfg.DeviceName,
-			Manufacturer: m.cfg.DeviceManufacturer,
-			Model:        m.cfg.DeviceModel,
-		},
+		JSONAttributesTopic: m.AttributesTopic(sensor),
+		Device:              m.stationDevice(),
 		Origin: OriginInfo{
 			Name:       "VEVOR Weatherbridge",
 			SWVersion:  Version,
@@ -191,6 +570,38 @@ func (m *MQTTClient) PublishSensorConfig(sensor *SensorDefinition) error {
 		},
 	}
 
+	if sensor.Bridge {
+		payload.Device = m.bridgeDevice()
+	}
+
+	// With the station watchdog, weather sensors are only available while
+	// both the bridge and the station are online. Diagnostics stay available
+	// with the bridge, e.g. to show when the last packet arrived.
+	if m.cfg.StationTimeout > 0 && sensor.stationData() {
+		payload.AvailabilityTopic = ""
+		payload.Availability = []Availability{
+			{Topic: m.AvailabilityTopic()},
+			{Topic: m.StationTopic()},
+		}
+		payload.AvailabilityMode = "all"
+	}
+
+	// Let Home Assistant mark values unavailable when no update arrives
+	if m.cfg.SensorExpireAfter > 0 && sensor.stationData() {
+		payload.ExpireAfter = int(m.cfg.SensorExpireAfter.Seconds())
+	}
+
+	// In JSON state mode all sensors share one state document. Sensors missing
+	// from a document (e.g. derived values without enough history) become
+	// unknown instead of failing to render.
+	if m.jsonState() {
+		payload.StateTopic = m.StateTopic(nil)
+		payload.JSONAttributesTopic = m.StateTopic(nil)
+		payload.ValueTemplate = fmt.Sprintf("{{ value_json.%[1]s if '%[1]s' in value_json else none }}", sensor.ID)
+		payload.JSONAttributesTemplate = fmt.Sprintf(
+			"{{ (value_json.attributes.%[1]s if '%[1]s' in value_json.attributes else {}) | tojson }}", sensor.ID)
+	}
+
 	// Set device class if defined
 	if sensor.DeviceClass != nil {
 		payload.DeviceClass = *sensor.DeviceClass
@@ -211,58 +622,371 @@ func (m *MQTTClient) PublishSensorConfig(sensor *SensorDefinition) error {
 		payload.SuggestedDisplayPrecision = sensor.Precision
 	}
 
+	// Diagnostic sensors are listed separately on the device page
+	payload.EntityCategory = sensor.EntityCategory
+
 	data, err := json.Marshal(payload)
 	if err != nil {
-		return fmt.Errorf("failed to marshal config payload: %w", err)
+		return nil, fmt.Errorf("failed to marshal config payload: %w", err)
+	}
+	return data, nil
+}
+
+// stationDevice returns the device info of the weather station, connected
+// through the bridge device.
+func (m *MQTTClient) stationDevice() DeviceInfo {
+	m.mu.RLock()
+	firmware := m.firmware
+	m.mu.RUnlock()
+
+	return DeviceInfo{
+		Identifiers:   []string{m.cfg.DeviceID},
+		Name:          m.cfg.DeviceName,
+		Manufacturer:  m.cfg.DeviceManufacturer,
+		Model:         m.cfg.DeviceModel,
+		SWVersion:     firmware,
+		SuggestedArea: m.cfg.DeviceSuggestedArea,
+		ViaDevice:     m.bridgeID(),
+	}
+}
+
+// bridgeDevice returns the device info of the bridge itself.
+func (m *MQTTClient) bridgeDevice() DeviceInfo {
+	return DeviceInfo{
+		Identifiers:      []string{m.bridgeID()},
+		Name:             fmt.Sprintf("%s Bridge", m.cfg.DeviceName),
+		Manufacturer:     "VEVOR Weatherbridge",
+		Model:            "Weather Station Bridge",
+		SWVersion:        Version,
+		ConfigurationURL: m.cfg.ConfigurationURL,
+	}
+}
+
+// bridgeID returns the device identifier of the bridge.
+func (m *MQTTClient) bridgeID() string {
+	return m.cfg.DeviceID + "_bridge"
+}
+
+// updateFirmware records the station firmware reported in a reading. Sensor
+// configs include it, so they are re-announced when it changes.
+func (m *MQTTClient) updateFirmware(reading Reading) {
+	for _, value := range reading.Values {
+		if value.Sensor.ID == "station_software" {
+			m.mu.Lock()
+			m.firmware = value.State
+			m.mu.Unlock()
+			return
+		}
+	}
+}
+
+// EnsureSensorConfig publishes the discovery config for a sensor unless the
+// same config was already announced on the current connection or the broker
+// does not use discovery topics.
+func (m *MQTTClient) EnsureSensorConfig(sensor *SensorDefinition) error {
+	// Brokers with flat topics get no discovery configs
+	if !m.discovery() {
+		return nil
+	}
+
+	data, err := m.discoveryPayload(sensor)
+	if err != nil {
+		return err
+	}
+
+	m.discoveryMu.Lock()
+	defer m.discoveryMu.Unlock()
+	if m.announced[sensor.ID] == string(data) {
+		return nil
 	}
+	return m.publishConfig(sensor, data)
+}
 
-	topic := m.ConfigTopic(sensor.ID)
-	token := m.client.Publish(topic, 1, true, data)
+// publishConfig publishes a discovery config payload and records it as
+// announced. The caller must hold discoveryMu.
+func (m *MQTTClient) publishConfig(sensor *SensorDefinition, data []byte) error {
+	m.known[sensor.ID] = *sensor
+
+	topic := m.ConfigTopic(sensor)
+	token := m.publishKind(m.client, KindConfig, topic, data)
 	token.Wait()
 	if token.Error() != nil {
+		delete(m.announced, sensor.ID)
 		return fmt.Errorf("failed to publish config: %w", token.Error())
 	}
+	m.announced[sensor.ID] = string(data)
+	if m.registry != nil {
+		m.registry.Add(topic)
+	}
 
 	slog.Debug("Published sensor config", "sensor", sensor.ID, "topic", topic)
 	return nil
 }
 
-// PublishSensorState publishes the state value for a sensor.
-func (m *MQTTClient) PublishSensorState(sensorID string, value string) error {
-	topic := m.StateTopic(sensorID)
-	token := m.client.Publish(topic, 1, true, value)
+// announceAll re-announces the discovery configs of all sensors seen so far,
+// e.g. after a reconnect.
+func (m *MQTTClient) announceAll() {
+	m.discoveryMu.Lock()
+	defer m.discoveryMu.Unlock()
+
+	clear(m.announced)
+	for id, sensor := range m.known {
+		data, err := m.discoveryPayload(&sensor)
+		if err == nil {
+			err = m.publishConfig(&sensor, data)
+		}
+		if err != nil {
+			slog.Error("Failed to re-announce sensor config", "sensor", id, "error", err)
+		}
+	}
+	slog.Debug("Announced sensor configs", "count", len(m.known))
+}
+
+// publishState publishes the state value for a sensor, or the state document
+// if sensor is nil, measured at measuredOn.
+func (m *MQTTClient) publishState(sensor *SensorDefinition, value, measuredOn string) error {
+	// The JSON state document carries the station data
+	station := sensor == nil || sensor.stationData()
+	topic := m.StateTopic(sensor)
+	m.stateMu.Lock()
+	m.lastState[topic] = lastMessage{payload: value, station: station}
+	m.stateMu.Unlock()
+
+	token := m.publishKindWithProperties(m.client, KindState, topic, value, m.stateProperties(station, measuredOn))
 	token.Wait()
 	if token.Error() != nil {
 		return fmt.Errorf("failed to publish state: %w", token.Error())
 	}
 
-	slog.Debug("Published sensor state", "sensor", sensorID, "value", value)
+	slog.Debug("Published sensor state", "topic", topic, "value", value)
 	return nil
 }
 
 // PublishSensorAttributes publishes the attributes for a sensor.
-func (m *MQTTClient) PublishSensorAttributes(sensorID string, attrs map[string]interface{}) error {
+func (m *MQTTClient) PublishSensorAttributes(sensor *SensorDefinition, attrs map[string]interface{}) error {
 	data, err := json.Marshal(attrs)
 	if err != nil {
 		return fmt.Errorf("failed to marshal attributes: %w", err)
 	}
 
-	topic := m.AttributesTopic(sensorID)
-	token := m.client.Publish(topic, 1, true, data)
+	topic := m.AttributesTopic(sensor)
+	m.stateMu.Lock()
+	m.lastAttrs[topic] = lastMessage{payload: data, station: sensor.stationData()}
+	m.stateMu.Unlock()
+
+	props := m.stateProperties(sensor.stationData(), measuredOn(attrs))
+	token := m.publishKindWithProperties(m.client, KindAttributes, topic, data, props)
 	token.Wait()
 	if token.Error() != nil {
 		return fmt.Errorf("failed to publish attributes: %w", token.Error())
 	}
 
-	slog.Debug("Published sensor attributes", "sensor", sensorID)
+	slog.Debug("Published sensor attributes", "sensor", sensor.ID)
 	return nil
 }
 
+// PublishReading publishes the config, state and attributes of every value in
+// a reading. While the broker is unreachable, readings are kept in the offline
+// buffer and published once the connection is back. Returns the number of
+// sensors published.
+func (m *MQTTClient) PublishReading(reading Reading) int {
+	m.publishMu.Lock()
+	defer m.publishMu.Unlock()
+
+	// Buffered readings go first so states are published in order
+	if !m.replayBufferLocked() {
+		m.bufferReading(reading)
+		return 0
+	}
+
+	publishedCount, ok := m.publishValues(reading)
+	if !ok {
+		m.bufferReading(reading)
+	}
+	return publishedCount
+}
+
+// publishValues publishes all values of a reading. Returns the number of
+// sensors published and false if the connection was lost on the way.
+func (m *MQTTClient) publishValues(reading Reading) (int, bool) {
+	m.updateFirmware(reading)
+
+	if m.jsonState() {
+		return m.publishDocument(reading)
+	}
+
+	publishedCount := 0
+	for _, value := range reading.Values {
+		if !m.IsConnected() {
+			return publishedCount, false
+		}
+		if m.publishValue(&value) {
+			publishedCount++
+		}
+	}
+	return publishedCount, publishedCount == len(reading.Values) || m.IsConnected()
+}
+
+// publishDocument publishes all values of a reading as a single JSON state
+// document, with each sensor's attributes nested under "attributes". Returns
+// the number of sensors published and false if the connection was lost.
+func (m *MQTTClient) publishDocument(reading Reading) (int, bool) {
+	if !m.IsConnected() {
+		return 0, false
+	}
+
+	doc := make(map[string]interface{}, len(reading.Values)+1)
+	attrs := make(map[string]map[string]interface{}, len(reading.Values))
+	measured := ""
+	for _, value := range reading.Values {
+		sensor := &value.Sensor
+		if err := m.EnsureSensorConfig(sensor); err != nil {
+			slog.Error("Failed to publish sensor config", "sensor", sensor.ID, "error", err)
+			continue
+		}
+		doc[sensor.ID] = jsonStateValue(value.State)
+		attrs[sensor.ID] = value.Attributes
+		if measured == "" {
+			measured = measuredOn(value.Attributes)
+		}
+	}
+	if len(attrs) == 0 {
+		return 0, m.IsConnected()
+	}
+	doc["attributes"] = attrs
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		slog.Error("Failed to marshal state document", "error", err)
+		return 0, true
+	}
+	if err := m.publishState(nil, string(data), measured); err != nil {
+		slog.Error("Failed to publish state document", "error", err)
+		return 0, m.IsConnected()
+	}
+
+	slog.Debug("Published state document", "sensors", len(attrs))
+	return len(attrs), true
+}
+
+// jsonStateValue returns numeric states as JSON numbers and others as strings.
+func jsonStateValue(state string) interface{} {
+	if _, err := strconv.ParseFloat(state, 64); err == nil && json.Valid([]byte(state)) {
+		return json.Number(state)
+	}
+	return state
+}
+
+// bufferReading stores a reading in the offline buffer.
+func (m *MQTTClient) bufferReading(reading Reading) {
+	if m.buffer == nil {
+		slog.Warn("MQTT disconnected, dropping reading", "sensors", len(reading.Values))
+		return
+	}
+	if err := m.buffer.Append(reading); err != nil {
+		slog.Error("Failed to buffer reading", "error", err)
+		return
+	}
+	slog.Info("MQTT disconnected, buffered reading", "buffered", m.buffer.Len())
+}
+
+// replayBuffer publishes the readings buffered while disconnected.
+func (m *MQTTClient) replayBuffer() {
+	m.publishMu.Lock()
+	defer m.publishMu.Unlock()
+	m.replayBufferLocked()
+}
+
+// replayBufferLocked publishes buffered readings in order, keeping those not
+// published if the connection is lost again. Returns true if the buffer is
+// empty afterwards. The caller must hold publishMu.
+func (m *MQTTClient) replayBufferLocked() bool {
+	if m.buffer == nil || m.buffer.Len() == 0 {
+		return true
+	}
+	if !m.IsConnected() {
+		return false
+	}
+
+	readings, err := m.buffer.Readings()
+	if err != nil {
+		slog.Error("Failed to read offline buffer", "error", err)
+		return false
+	}
+
+	slog.Info("Replaying buffered readings", "count", len(readings))
+	for i, reading := range readings {
+		if _, ok := m.publishValues(reading); !ok {
+			if err := m.buffer.Replace(readings[i:]); err != nil {
+				slog.Error("Failed to update offline buffer", "error", err)
+			}
+			slog.Warn("MQTT disconnected during replay", "remaining", len(readings)-i)
+			return false
+		}
+	}
+
+	if err := m.buffer.Replace(nil); err != nil {
+		slog.Error("Failed to clear offline buffer", "error", err)
+		return false
+	}
+	return true
+}
+
+// publishValue publishes a single sensor value. Returns true on success.
+func (m *MQTTClient) publishValue(value *SensorValue) bool {
+	sensor := &value.Sensor
+
+	// Publish sensor config (only announced once per connection)
+	if err := m.EnsureSensorConfig(sensor); err != nil {
+		slog.Error("Failed to publish sensor config", "sensor", sensor.ID, "error", err)
+		return false
+	}
+
+	// Publish sensor state
+	if err := m.publishState(sensor, value.State, measuredOn(value.Attributes)); err != nil {
+		slog.Error("Failed to publish sensor state", "sensor", sensor.ID, "error", err)
+		return false
+	}
+
+	// Publish attributes
+	if err := m.PublishSensorAttributes(sensor, value.Attributes); err != nil {
+		slog.Error("Failed to publish sensor attributes", "sensor", sensor.ID, "error", err)
+		return false
+	}
+
+	slog.Debug("Published sensor data", "sensor", sensor.ID, "value", value.State)
+	return true
+}
+
+// republishStates publishes the last known state and attributes of every sensor.
+func (m *MQTTClient) republishStates() {
+	m.stateMu.Lock()
+	states := maps.Clone(m.lastState)
+	attrs := maps.Clone(m.lastAttrs)
+	m.stateMu.Unlock()
+
+	for topic, msg := range states {
+		token := m.publishKindWithProperties(m.client, KindState, topic, msg.payload, m.stateProperties(msg.station, ""))
+		token.Wait()
+		if token.Error() != nil {
+			slog.Error("Failed to re-publish sensor state", "topic", topic, "error", token.Error())
+		}
+	}
+	for topic, msg := range attrs {
+		token := m.publishKindWithProperties(m.client, KindAttributes, topic, msg.payload, m.stateProperties(msg.station, ""))
+		token.Wait()
+		if token.Error() != nil {
+			slog.Error("Failed to re-publish sensor attributes", "topic", topic, "error", token.Error())
+		}
+	}
+	slog.Debug("Re-published sensor states", "count", len(states))
+}
+
 // Close disconnects the MQTT client gracefully.
 func (m *MQTTClient) Close() {
 	// Publish offline status before disconnecting
 	availTopic := m.AvailabilityTopic()
-	token := m.client.Publish(availTopic, 1, true, "offline")
+	token := m.publishKind(m.client, KindAvailability, availTopic, "offline")
 	if token.WaitTimeout(2 * time.Second) {
 		if token.Error() != nil {
 			slog.Error("Failed to publish offline status", "topic", availTopic, "error", token.Error())
@@ -274,5 +998,5 @@ func (m *MQTTClient) Close() {
 	}
 
 	m.client.Disconnect(1000)
-	slog.Info("MQTT disconnected")
+	slog.Info("MQTT disconnected", "broker", m.broker.Name)
 }
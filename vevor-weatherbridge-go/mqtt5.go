@@ -0,0 +1,383 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+// Copyright (C) 2025 Lenucksi
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/eclipse/paho.golang/autopaho"
+	"github.com/eclipse/paho.golang/paho"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// MQTT protocol versions supported by a broker.
+const (
+	MQTTVersion3 = 3 // MQTT 3.1.1
+	MQTTVersion5 = 5
+)
+
+// publishTimeout bounds how long an MQTT 5 publish waits for the broker.
+const publishTimeout = 30 * time.Second
+
+// errConnectionLost is reported to the connection lost handler by the MQTT 5
+// client, which does not pass on the cause.
+var errConnectionLost = errors.New("connection lost")
+
+// publishProperties are MQTT 5 properties attached to a published message.
+type publishProperties struct {
+	// MessageExpiry makes the broker discard the message once it is older
+	// (0 means it never expires)
+	MessageExpiry time.Duration
+	// User properties, sent sorted by key
+	User map[string]string
+}
+
+// propertyPublisher is implemented by clients that can attach MQTT 5
+// properties to a message. Other clients publish without them.
+type propertyPublisher interface {
+	PublishWithProperties(topic string, qos byte, retained bool, payload interface{}, props publishProperties) mqtt.Token
+}
+
+// mqtt5Client is an MQTT 5 client with the interface of the paho MQTT 3
+// client, so MQTTClient can use either. It reconnects automatically and calls
+// the connect and connection lost handlers like the paho MQTT 3 client.
+type mqtt5Client struct {
+	config         autopaho.ClientConfig
+	onConnect      mqtt.OnConnectHandler
+	connectionLost mqtt.ConnectionLostHandler
+
+	// ctx ends all operations on Disconnect. cm is set by Connect and by
+	// the connection callbacks, which may run first; both hold mu.
+	ctx       context.Context
+	cancel    context.CancelFunc
+	cm        *autopaho.ConnectionManager
+	handlers  map[string]mqtt.MessageHandler // by topic
+	connected bool
+	mu        sync.RWMutex
+}
+
+// newMQTT5Client creates an MQTT 5 client for the broker of m. The client
+// connects on Connect.
+func newMQTT5Client(m *MQTTClient, u *url.URL, tlsConfig *tls.Config, clientID string) *mqtt5Client {
+	c := &mqtt5Client{
+		onConnect:      m.onConnect,
+		connectionLost: m.onConnectionLost,
+		handlers:       make(map[string]mqtt.MessageHandler),
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+
+	availability := m.messages[KindAvailability]
+	c.config = autopaho.ClientConfig{
+		ServerUrls:        []*url.URL{u},
+		TlsCfg:            tlsConfig,
+		KeepAlive:         60,
+		ConnectRetryDelay: 10 * time.Second,
+		ConnectTimeout:    10 * time.Second,
+		ConnectUsername:   m.broker.User,
+		ConnectPassword:   []byte(m.broker.Password),
+		WillMessage: &paho.WillMessage{
+			Topic:   m.AvailabilityTopic(),
+			Payload: []byte("offline"),
+			QoS:     availability.qos,
+			Retain:  availability.retain,
+		},
+		OnConnectionUp:   c.connectionUp,
+		OnConnectionDown: c.connectionDown,
+		OnConnectError: func(err error) {
+			slog.Debug("MQTT connection attempt failed", "broker", m.broker.Name, "error", err)
+		},
+		ClientConfig: paho.ClientConfig{
+			ClientID:          clientID,
+			OnPublishReceived: []func(paho.PublishReceived) (bool, error){c.received},
+		},
+	}
+	return c
+}
+
+// connectionUp is called by autopaho when the connection is established.
+func (c *mqtt5Client) connectionUp(cm *autopaho.ConnectionManager, _ *paho.Connack) {
+	c.mu.Lock()
+	c.cm = cm
+	c.connected = true
+	c.mu.Unlock()
+
+	// autopaho callbacks must not block
+	go c.onConnect(c)
+}
+
+// connectionDown is called by autopaho when the connection is lost.
+func (c *mqtt5Client) connectionDown() bool {
+	c.mu.Lock()
+	c.connected = false
+	c.mu.Unlock()
+
+	go c.connectionLost(c, errConnectionLost)
+	return true
+}
+
+// received passes an incoming message to the handler of its topic.
+func (c *mqtt5Client) received(pr paho.PublishReceived) (bool, error) {
+	c.mu.RLock()
+	handler, ok := c.handlers[pr.Packet.Topic]
+	c.mu.RUnlock()
+	if !ok {
+		return false, nil
+	}
+	handler(c, &mqtt5Message{packet: pr.Packet})
+	return true, nil
+}
+
+// manager returns the autopaho connection manager, or nil before Connect.
+func (c *mqtt5Client) manager() *autopaho.ConnectionManager {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cm
+}
+
+// IsConnected returns true if the client is connected.
+func (c *mqtt5Client) IsConnected() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.connected
+}
+
+// IsConnectionOpen returns true if the client is connected.
+func (c *mqtt5Client) IsConnectionOpen() bool {
+	return c.IsConnected()
+}
+
+// Connect starts connecting in the background, retrying until Disconnect is
+// called. The token completes once the first connection is up.
+func (c *mqtt5Client) Connect() mqtt.Token {
+	cm, err := autopaho.NewConnection(c.ctx, c.config)
+	if err != nil {
+		return completedToken(err)
+	}
+	c.mu.Lock()
+	c.cm = cm
+	c.mu.Unlock()
+
+	return newMQTT5Token(func() error {
+		return cm.AwaitConnection(c.ctx)
+	})
+}
+
+// Disconnect disconnects from the broker, waiting up to quiesce milliseconds.
+func (c *mqtt5Client) Disconnect(quiesce uint) {
+	cm := c.manager()
+	if cm == nil {
+		c.cancel()
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(quiesce)*time.Millisecond)
+	defer cancel()
+	if err := cm.Disconnect(ctx); err != nil {
+		slog.Debug("MQTT disconnect did not complete", "error", err)
+	}
+	c.cancel()
+}
+
+// Publish publishes a message without properties.
+func (c *mqtt5Client) Publish(topic string, qos byte, retained bool, payload interface{}) mqtt.Token {
+	return c.PublishWithProperties(topic, qos, retained, payload, publishProperties{})
+}
+
+// PublishWithProperties publishes a message with MQTT 5 properties.
+func (c *mqtt5Client) PublishWithProperties(topic string, qos byte, retained bool, payload interface{}, props publishProperties) mqtt.Token {
+	var data []byte
+	switch p := payload.(type) {
+	case string:
+		data = []byte(p)
+	case []byte:
+		data = p
+	default:
+		return completedToken(fmt.Errorf("unsupported payload type %T", payload))
+	}
+	cm := c.manager()
+	if cm == nil {
+		return completedToken(autopaho.ConnectionDownError)
+	}
+
+	publish := &paho.Publish{
+		Topic:      topic,
+		QoS:        qos,
+		Retain:     retained,
+		Payload:    data,
+		Properties: pahoPublishProperties(props),
+	}
+	return newMQTT5Token(func() error {
+		ctx, cancel := context.WithTimeout(c.ctx, publishTimeout)
+		defer cancel()
+		_, err := cm.Publish(ctx, publish)
+		return err
+	})
+}
+
+// pahoPublishProperties converts message properties for paho.
+func pahoPublishProperties(props publishProperties) *paho.PublishProperties {
+	p := &paho.PublishProperties{}
+	if props.MessageExpiry > 0 {
+		expiry := uint32(props.MessageExpiry / time.Second)
+		p.MessageExpiry = &expiry
+	}
+	for _, key := range slices.Sorted(maps.Keys(props.User)) {
+		p.User.Add(key, props.User[key])
+	}
+	return p
+}
+
+// Subscribe subscribes to a topic. Only messages published to exactly that
+// topic are passed to callback; wildcards are not supported.
+func (c *mqtt5Client) Subscribe(topic string, qos byte, callback mqtt.MessageHandler) mqtt.Token {
+	return c.SubscribeMultiple(map[string]byte{topic: qos}, callback)
+}
+
+// SubscribeMultiple subscribes to several topics with the same callback.
+func (c *mqtt5Client) SubscribeMultiple(filters map[string]byte, callback mqtt.MessageHandler) mqtt.Token {
+	cm := c.manager()
+	if cm == nil {
+		return completedToken(autopaho.ConnectionDownError)
+	}
+
+	subscribe := &paho.Subscribe{}
+	c.mu.Lock()
+	for topic, qos := range filters {
+		c.handlers[topic] = callback
+		subscribe.Subscriptions = append(subscribe.Subscriptions, paho.SubscribeOptions{Topic: topic, QoS: qos})
+	}
+	c.mu.Unlock()
+
+	return newMQTT5Token(func() error {
+		ctx, cancel := context.WithTimeout(c.ctx, publishTimeout)
+		defer cancel()
+		_, err := cm.Subscribe(ctx, subscribe)
+		return err
+	})
+}
+
+// Unsubscribe removes the subscriptions to the given topics.
+func (c *mqtt5Client) Unsubscribe(topics ...string) mqtt.Token {
+	cm := c.manager()
+	if cm == nil {
+		return completedToken(autopaho.ConnectionDownError)
+	}
+
+	c.mu.Lock()
+	for _, topic := range topics {
+		delete(c.handlers, topic)
+	}
+	c.mu.Unlock()
+
+	return newMQTT5Token(func() error {
+		ctx, cancel := context.WithTimeout(c.ctx, publishTimeout)
+		defer cancel()
+		_, err := cm.Unsubscribe(ctx, &paho.Unsubscribe{Topics: topics})
+		return err
+	})
+}
+
+// AddRoute passes messages published to topic to callback without subscribing.
+func (c *mqtt5Client) AddRoute(topic string, callback mqtt.MessageHandler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.handlers[topic] = callback
+}
+
+// OptionsReader is not supported by the MQTT 5 client and returns an empty
+// reader.
+func (c *mqtt5Client) OptionsReader() mqtt.ClientOptionsReader {
+	return mqtt.ClientOptionsReader{}
+}
+
+// mqtt5Token is a paho MQTT 3 token completed by an MQTT 5 operation.
+type mqtt5Token struct {
+	done chan struct{}
+	err  error
+}
+
+// newMQTT5Token runs op in the background and completes with its result.
+func newMQTT5Token(op func() error) *mqtt5Token {
+	t := &mqtt5Token{done: make(chan struct{})}
+	go func() {
+		t.err = op()
+		close(t.done)
+	}()
+	return t
+}
+
+// completedToken returns a token completed with err.
+func completedToken(err error) *mqtt5Token {
+	t := &mqtt5Token{done: make(chan struct{}), err: err}
+	close(t.done)
+	return t
+}
+
+// Wait waits for the operation to complete.
+func (t *mqtt5Token) Wait() bool {
+	<-t.done
+	return true
+}
+
+// WaitTimeout waits up to d for the operation. Returns false on timeout.
+func (t *mqtt5Token) WaitTimeout(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-t.done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// Done returns a channel closed when the operation is complete.
+func (t *mqtt5Token) Done() <-chan struct{} {
+	return t.done
+}
+
+// Error returns the error of the completed operation.
+func (t *mqtt5Token) Error() error {
+	select {
+	case <-t.done:
+		return t.err
+	default:
+		return nil
+	}
+}
+
+// mqtt5Message is a received MQTT 5 message with the interface of a paho
+// MQTT 3 message.
+type mqtt5Message struct {
+	packet *paho.Publish
+}
+
+func (m *mqtt5Message) Duplicate() bool   { return m.packet.Duplicate() }
+func (m *mqtt5Message) Qos() byte         { return m.packet.QoS }
+func (m *mqtt5Message) Retained() bool    { return m.packet.Retain }
+func (m *mqtt5Message) Topic() string     { return m.packet.Topic }
+func (m *mqtt5Message) MessageID() uint16 { return m.packet.PacketID }
+func (m *mqtt5Message) Payload() []byte   { return m.packet.Payload }
+func (m *mqtt5Message) Ack()              {}
@@ -0,0 +1,104 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+// Copyright (C) 2025 Lenucksi
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/eclipse/paho.golang/paho"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+func TestPahoPublishProperties(t *testing.T) {
+	props := pahoPublishProperties(publishProperties{
+		MessageExpiry: 10 * time.Minute,
+		User:          map[string]string{"station_id": "weather_station", "measured_on": "2025-12-01T12:00:00Z"},
+	})
+
+	if props.MessageExpiry == nil || *props.MessageExpiry != 600 {
+		t.Errorf("MessageExpiry = %v, want 600", props.MessageExpiry)
+	}
+	want := paho.UserProperties{
+		{Key: "measured_on", Value: "2025-12-01T12:00:00Z"},
+		{Key: "station_id", Value: "weather_station"},
+	}
+	if len(props.User) != len(want) {
+		t.Fatalf("User = %v, want %v", props.User, want)
+	}
+	for i := range want {
+		if props.User[i] != want[i] {
+			t.Errorf("User[%d] = %v, want %v", i, props.User[i], want[i])
+		}
+	}
+
+	if empty := pahoPublishProperties(publishProperties{}); empty.MessageExpiry != nil || len(empty.User) != 0 {
+		t.Errorf("empty properties = %+v, want none set", empty)
+	}
+}
+
+func TestMQTT5Token(t *testing.T) {
+	release := make(chan struct{})
+	errFailed := errors.New("failed")
+	token := newMQTT5Token(func() error {
+		<-release
+		return errFailed
+	})
+
+	if token.WaitTimeout(10 * time.Millisecond) {
+		t.Fatal("WaitTimeout() = true before the operation completed")
+	}
+	if token.Error() != nil {
+		t.Errorf("Error() = %v before the operation completed, want nil", token.Error())
+	}
+
+	close(release)
+	if !token.WaitTimeout(time.Second) {
+		t.Fatal("WaitTimeout() = false after the operation completed")
+	}
+	if !errors.Is(token.Error(), errFailed) {
+		t.Errorf("Error() = %v, want %v", token.Error(), errFailed)
+	}
+}
+
+func TestMQTT5ClientRoutesMessages(t *testing.T) {
+	m := newMQTTClient(testConfig(), testConfig().PrimaryBroker())
+	c := newMQTT5Client(m, nil, nil, "test")
+
+	var got []string
+	c.AddRoute("homeassistant/status", func(_ mqtt.Client, msg mqtt.Message) {
+		got = append(got, string(msg.Payload()))
+	})
+
+	for _, topic := range []string{"homeassistant/status", "homeassistant/other"} {
+		handled, err := c.received(paho.PublishReceived{Packet: &paho.Publish{Topic: topic, Payload: []byte("online")}})
+		if err != nil {
+			t.Fatalf("received() error: %v", err)
+		}
+		if want := topic == "homeassistant/status"; handled != want {
+			t.Errorf("received(%q) = %v, want %v", topic, handled, want)
+		}
+	}
+	if len(got) != 1 || got[0] != "online" {
+		t.Errorf("delivered %v, want [online]", got)
+	}
+
+	if c.config.WillMessage == nil || c.config.WillMessage.Topic != m.AvailabilityTopic() || string(c.config.WillMessage.Payload) != "offline" {
+		t.Errorf("will message = %+v, want offline on %s", c.config.WillMessage, m.AvailabilityTopic())
+	}
+}
@@ -17,11 +17,178 @@
 package main
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
 )
 
+// fakeToken is a completed paho token.
+type fakeToken struct {
+	err error
+}
+
+func (t *fakeToken) Wait() bool                     { return true }
+func (t *fakeToken) WaitTimeout(time.Duration) bool { return true }
+func (t *fakeToken) Done() <-chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}
+func (t *fakeToken) Error() error { return t.err }
+
+// fakeMessage is a published MQTT message recorded by fakeClient.
+type fakeMessage struct {
+	Topic    string
+	QoS      byte
+	Retained bool
+	Payload  string
+}
+
+// fakeClient is a paho client that records published messages and subscriptions.
+type fakeClient struct {
+	messages      []fakeMessage
+	subscriptions map[string]mqtt.MessageHandler
+	err           error
+	mu            sync.Mutex
+}
+
+func (c *fakeClient) IsConnected() bool       { return true }
+func (c *fakeClient) IsConnectionOpen() bool  { return true }
+func (c *fakeClient) Connect() mqtt.Token     { return &fakeToken{} }
+func (c *fakeClient) Disconnect(quiesce uint) {}
+func (c *fakeClient) Publish(topic string, qos byte, retained bool, payload interface{}) mqtt.Token {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var p string
+	switch v := payload.(type) {
+	case string:
+		p = v
+	case []byte:
+		p = string(v)
+	}
+	c.messages = append(c.messages, fakeMessage{Topic: topic, QoS: qos, Retained: retained, Payload: p})
+	return &fakeToken{err: c.err}
+}
+func (c *fakeClient) Subscribe(topic string, _ byte, callback mqtt.MessageHandler) mqtt.Token {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.subscriptions == nil {
+		c.subscriptions = make(map[string]mqtt.MessageHandler)
+	}
+	c.subscriptions[topic] = callback
+	return &fakeToken{}
+}
+func (c *fakeClient) SubscribeMultiple(map[string]byte, mqtt.MessageHandler) mqtt.Token {
+	return &fakeToken{}
+}
+func (c *fakeClient) Unsubscribe(...string) mqtt.Token        { return &fakeToken{} }
+func (c *fakeClient) AddRoute(string, mqtt.MessageHandler)    {}
+func (c *fakeClient) OptionsReader() mqtt.ClientOptionsReader { return mqtt.ClientOptionsReader{} }
+
+// published returns the messages published to a topic.
+func (c *fakeClient) published(topic string) []fakeMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var result []fakeMessage
+	for _, msg := range c.messages {
+		if msg.Topic == topic {
+			result = append(result, msg)
+		}
+	}
+	return result
+}
+
+// deliver calls the subscription handler for topic with payload.
+func (c *fakeClient) deliver(t *testing.T, topic, payload string) {
+	t.Helper()
+	c.mu.Lock()
+	callback, ok := c.subscriptions[topic]
+	c.mu.Unlock()
+	if !ok {
+		t.Fatalf("no subscription for %q", topic)
+	}
+	callback(c, &fakeReceived{topic: topic, payload: []byte(payload)})
+}
+
+// waitForPublished waits until at least n messages were published to topic.
+func (c *fakeClient) waitForPublished(t *testing.T, topic string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for len(c.published(topic)) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%q published %d times, want %d", topic, len(c.published(topic)), n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// fakeReceived is a message delivered to a subscription handler.
+type fakeReceived struct {
+	topic   string
+	payload []byte
+}
+
+func (m *fakeReceived) Duplicate() bool   { return false }
+func (m *fakeReceived) Qos() byte         { return 1 }
+func (m *fakeReceived) Retained() bool    { return false }
+func (m *fakeReceived) Topic() string     { return m.topic }
+func (m *fakeReceived) MessageID() uint16 { return 0 }
+func (m *fakeReceived) Payload() []byte   { return m.payload }
+func (m *fakeReceived) Ack()              {}
+
+// fakePropertyClient is a fakeClient that also records MQTT 5 properties.
+type fakePropertyClient struct {
+	*fakeClient
+	properties map[string][]publishProperties // by topic
+}
+
+func (c *fakePropertyClient) PublishWithProperties(topic string, qos byte, retained bool, payload interface{}, props publishProperties) mqtt.Token {
+	c.mu.Lock()
+	if c.properties == nil {
+		c.properties = make(map[string][]publishProperties)
+	}
+	c.properties[topic] = append(c.properties[topic], props)
+	c.mu.Unlock()
+	return c.Publish(topic, qos, retained, payload)
+}
+
+// newTestMQTTClient returns an MQTTClient publishing to a fake paho client.
+func newTestMQTTClient(cfg *Config) (*MQTTClient, *fakeClient) {
+	fake := &fakeClient{}
+	m := newMQTTClient(cfg, cfg.PrimaryBroker())
+	m.client = fake
+	return m, fake
+}
+
+// testConfig returns a minimal configuration for MQTT tests.
+func testConfig() *Config {
+	return &Config{
+		MQTTPrefix:         "homeassistant",
+		MQTTQoS:            1,
+		DeviceID:           "weather_station",
+		DeviceName:         "Weather Station",
+		DeviceManufacturer: "VEVOR",
+		DeviceModel:        "7-in-1 Weather Station",
+		Units:              "metric",
+		Timezone:           time.UTC,
+	}
+}
+
 func TestDiscoveryPayloadJSON(t *testing.T) {
 	cfg := &Config{
 		MQTTPrefix:         "homeassistant",
@@ -127,6 +294,108 @@ func TestDiscoveryPayloadOmitsEmptyFields(t *testing.T) {
 	if _, ok := result["state_class"]; ok {
 		t.Error("state_class should be omitted when empty")
 	}
+	if _, ok := result["entity_category"]; ok {
+		t.Error("entity_category should be omitted when empty")
+	}
+}
+
+func TestDiagnosticSensorDiscovery(t *testing.T) {
+	m, _ := newTestMQTTClient(testConfig())
+
+	for _, sensor := range DiagnosticSensorDefinitions {
+		data, err := m.discoveryPayload(&sensor)
+		if err != nil {
+			t.Fatalf("discoveryPayload(%s) error: %v", sensor.ID, err)
+		}
+		var payload DiscoveryPayload
+		if err := json.Unmarshal(data, &payload); err != nil {
+			t.Fatalf("invalid payload: %v", err)
+		}
+		if payload.EntityCategory != "diagnostic" {
+			t.Errorf("%s entity_category = %q, want diagnostic", sensor.ID, payload.EntityCategory)
+		}
+		device := "weather_station"
+		if sensor.ID == "bridge_version" {
+			device = "weather_station_bridge"
+		}
+		if payload.Device.Identifiers[0] != device {
+			t.Errorf("%s device = %v, want %s", sensor.ID, payload.Device.Identifiers, device)
+		}
+	}
+}
+
+func TestBinarySensorDiscovery(t *testing.T) {
+	m, _ := newTestMQTTClient(testConfig())
+
+	for _, sensor := range BinarySensorDefinitions {
+		data, err := m.discoveryPayload(&sensor)
+		if err != nil {
+			t.Fatalf("discoveryPayload(%s) error: %v", sensor.ID, err)
+		}
+		var result map[string]interface{}
+		if err := json.Unmarshal(data, &result); err != nil {
+			t.Fatalf("invalid payload: %v", err)
+		}
+		for _, field := range []string{"unit_of_measurement", "state_class"} {
+			if _, ok := result[field]; ok {
+				t.Errorf("%s: %s should be omitted for binary sensors", sensor.ID, field)
+			}
+		}
+		if want := "homeassistant/binary_sensor/weather_station_" + sensor.ID + "/state"; result["state_topic"] != want {
+			t.Errorf("%s state_topic = %v, want %s", sensor.ID, result["state_topic"], want)
+		}
+	}
+}
+
+func TestDeviceHierarchy(t *testing.T) {
+	cfg := testConfig()
+	cfg.DeviceSuggestedArea = "Garden"
+	cfg.ConfigurationURL = "homeassistant://hassio/addon/vevor-weatherbridge-go/info"
+	m, fake := newTestMQTTClient(cfg)
+	m.onConnect(fake)
+
+	temperature := *GetSensorByQueryParam("tempf")
+	m.PublishReading(Reading{
+		ReceivedAt: time.Now(),
+		Values: []SensorValue{
+			{Sensor: temperature, State: "21.5", Attributes: map[string]interface{}{}},
+			{Sensor: DiagnosticSensorDefinitions[3], State: "EasyWeatherV1.6.4", Attributes: map[string]interface{}{}},
+			{Sensor: DiagnosticSensorDefinitions[5], State: Version, Attributes: map[string]interface{}{}},
+		},
+	})
+
+	device := func(sensorID string) DeviceInfo {
+		t.Helper()
+		configs := fake.published(m.ConfigTopic(GetSensorByID(sensorID)))
+		if len(configs) == 0 {
+			t.Fatalf("no config published for %s", sensorID)
+		}
+		var payload DiscoveryPayload
+		if err := json.Unmarshal([]byte(configs[len(configs)-1].Payload), &payload); err != nil {
+			t.Fatalf("invalid config payload: %v", err)
+		}
+		return payload.Device
+	}
+
+	station := device(temperature.ID)
+	wantStation := DeviceInfo{
+		Identifiers:   []string{"weather_station"},
+		Name:          "Weather Station",
+		Manufacturer:  "VEVOR",
+		Model:         "7-in-1 Weather Station",
+		SWVersion:     "EasyWeatherV1.6.4",
+		SuggestedArea: "Garden",
+		ViaDevice:     "weather_station_bridge",
+	}
+	if !reflect.DeepEqual(station, wantStation) {
+		t.Errorf("station device = %+v, want %+v", station, wantStation)
+	}
+
+	bridge := device("bridge_version")
+	if bridge.Identifiers[0] != wantStation.ViaDevice || bridge.SWVersion != Version ||
+		bridge.ConfigurationURL != cfg.ConfigurationURL || bridge.ViaDevice != "" {
+		t.Errorf("bridge device = %+v", bridge)
+	}
 }
 
 func TestDiscoveryPayloadStateClass(t *testing.T) {
@@ -216,23 +485,25 @@ func TestMQTTClientTopics(t *testing.T) {
 		DeviceID:   "weather_station",
 	}
 
-	// Create a mock client struct just to test topic generation
-	m := &MQTTClient{cfg: cfg}
+	// Create a client without connection just to test topic generation
+	m := newMQTTClient(cfg, cfg.PrimaryBroker())
 
 	tests := []struct {
 		name     string
-		fn       func(string) string
+		fn       func(*SensorDefinition) string
 		sensorID string
 		expected string
 	}{
 		{"ConfigTopic", m.ConfigTopic, "temperature", "homeassistant/sensor/weather_station_temperature/config"},
 		{"StateTopic", m.StateTopic, "temperature", "homeassistant/sensor/weather_station_temperature/state"},
 		{"AttributesTopic", m.AttributesTopic, "humidity", "homeassistant/sensor/weather_station_humidity/attributes"},
+		{"BinaryConfigTopic", m.ConfigTopic, "raining", "homeassistant/binary_sensor/weather_station_raining/config"},
+		{"BinaryStateTopic", m.StateTopic, "frost_risk", "homeassistant/binary_sensor/weather_station_frost_risk/state"},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := tt.fn(tt.sensorID)
+			result := tt.fn(GetSensorByID(tt.sensorID))
 			if result != tt.expected {
 				t.Errorf("%s(%q) = %q, want %q", tt.name, tt.sensorID, result, tt.expected)
 			}
@@ -240,13 +511,59 @@ func TestMQTTClientTopics(t *testing.T) {
 	}
 }
 
+func TestMQTTClientFlatTopics(t *testing.T) {
+	cfg := &Config{DeviceID: "weather_station"}
+	m := newMQTTClient(cfg, BrokerConfig{Name: "data", Prefix: "weather", TopicStyle: TopicStyleFlat})
+
+	tests := map[string]string{
+		m.AvailabilityTopic():                              "weather/weather_station/availability",
+		m.StateTopic(GetSensorByID("temperature")):         "weather/weather_station/temperature",
+		m.AttributesTopic(GetSensorByID("wind_direction")): "weather/weather_station/wind_direction/attributes",
+	}
+	for got, want := range tests {
+		if got != want {
+			t.Errorf("topic = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestFlatBrokerSkipsDiscovery(t *testing.T) {
+	fake := &fakeClient{}
+	m := newMQTTClient(testConfig(), BrokerConfig{Name: "data", Prefix: "weather", TopicStyle: TopicStyleFlat, QoS: 0})
+	m.client = fake
+
+	m.onConnect(fake)
+	published := m.PublishReading(Reading{
+		ReceivedAt: time.Now(),
+		Values:     []SensorValue{{Sensor: *GetSensorByQueryParam("tempf"), State: "21.5", Attributes: map[string]interface{}{}}},
+	})
+	if published != 1 {
+		t.Fatalf("PublishReading() = %d, want 1", published)
+	}
+
+	for _, msg := range fake.messages {
+		if strings.HasSuffix(msg.Topic, "/config") {
+			t.Errorf("flat broker published discovery config to %s", msg.Topic)
+		}
+		if msg.QoS != 0 {
+			t.Errorf("message to %s has QoS %d, want 0", msg.Topic, msg.QoS)
+		}
+	}
+	if len(fake.subscriptions) != 0 {
+		t.Errorf("flat broker subscribed to %v", fake.subscriptions)
+	}
+	if got := fake.published("weather/weather_station/temperature"); len(got) != 1 || got[0].Payload != "21.5" {
+		t.Errorf("state messages = %v", got)
+	}
+}
+
 func TestMQTTClientAvailabilityTopic(t *testing.T) {
 	cfg := &Config{
 		MQTTPrefix: "homeassistant",
 		DeviceID:   "my_weather",
 	}
 
-	m := &MQTTClient{cfg: cfg}
+	m := newMQTTClient(cfg, cfg.PrimaryBroker())
 	expected := "homeassistant/sensor/my_weather/availability"
 	result := m.AvailabilityTopic()
 
@@ -266,3 +583,486 @@ func TestConfigTimezoneLoading(t *testing.T) {
 		t.Errorf("Timezone = %v, want America/New_York", loc.String())
 	}
 }
+
+func TestEnsureSensorConfigAnnouncesOnce(t *testing.T) {
+	m, fake := newTestMQTTClient(testConfig())
+	sensor := GetSensorByQueryParam("tempf")
+	topic := m.ConfigTopic(sensor)
+
+	for i := 0; i < 3; i++ {
+		if err := m.EnsureSensorConfig(sensor); err != nil {
+			t.Fatalf("EnsureSensorConfig() error: %v", err)
+		}
+	}
+	if got := len(fake.published(topic)); got != 1 {
+		t.Errorf("config published %d times, want 1", got)
+	}
+
+	// A changed definition is announced again
+	m.cfg.Units = "imperial"
+	if err := m.EnsureSensorConfig(sensor); err != nil {
+		t.Fatalf("EnsureSensorConfig() error: %v", err)
+	}
+	if got := len(fake.published(topic)); got != 2 {
+		t.Errorf("config published %d times after unit change, want 2", got)
+	}
+}
+
+func TestOnConnectReannouncesKnownSensors(t *testing.T) {
+	m, fake := newTestMQTTClient(testConfig())
+	temperature := GetSensorByQueryParam("tempf")
+	humidity := GetSensorByQueryParam("humidity")
+
+	for _, sensor := range []*SensorDefinition{temperature, humidity} {
+		if err := m.EnsureSensorConfig(sensor); err != nil {
+			t.Fatalf("EnsureSensorConfig() error: %v", err)
+		}
+	}
+
+	m.onConnect(fake)
+
+	for _, sensor := range []*SensorDefinition{temperature, humidity} {
+		if got := len(fake.published(m.ConfigTopic(sensor))); got != 2 {
+			t.Errorf("%s config published %d times, want 2", sensor.ID, got)
+		}
+	}
+
+	// Already re-announced on this connection
+	if err := m.EnsureSensorConfig(temperature); err != nil {
+		t.Fatalf("EnsureSensorConfig() error: %v", err)
+	}
+	if got := len(fake.published(m.ConfigTopic(temperature))); got != 2 {
+		t.Errorf("config published %d times after reconnect, want 2", got)
+	}
+}
+
+func TestClearStaleDiscovery(t *testing.T) {
+	cfg := testConfig()
+	m, fake := newTestMQTTClient(cfg)
+	m.registry = NewDiscoveryRegistry(filepath.Join(t.TempDir(), "discovery.json"))
+
+	// Announced under an old device ID and for a sensor that no longer exists
+	staleTopics := []string{
+		"homeassistant/sensor/old_station_temperature/config",
+		"homeassistant/sensor/weather_station_removed/config",
+	}
+	for _, topic := range staleTopics {
+		m.registry.Add(topic)
+	}
+	temperature := GetSensorByQueryParam("tempf")
+	if err := m.EnsureSensorConfig(temperature); err != nil {
+		t.Fatalf("EnsureSensorConfig() error: %v", err)
+	}
+
+	m.onConnect(fake)
+
+	for _, topic := range staleTopics {
+		got := fake.published(topic)
+		if len(got) != 1 || got[0].Payload != "" || !got[0].Retained {
+			t.Errorf("%s published %+v, want one empty retained message", topic, got)
+		}
+	}
+	for _, msg := range fake.published(m.ConfigTopic(temperature)) {
+		if msg.Payload == "" {
+			t.Errorf("current sensor config was cleared")
+		}
+	}
+	if got := m.registry.Stale(m.configTopics()); len(got) != 0 {
+		t.Errorf("stale topics after clearing = %v, want none", got)
+	}
+}
+
+func TestHomeAssistantBirthRepublishes(t *testing.T) {
+	m, fake := newTestMQTTClient(testConfig())
+	sensor := GetSensorByQueryParam("tempf")
+
+	m.onConnect(fake)
+	if err := m.EnsureSensorConfig(sensor); err != nil {
+		t.Fatalf("EnsureSensorConfig() error: %v", err)
+	}
+	if err := m.publishState(sensor, "21.5", ""); err != nil {
+		t.Fatalf("publishState() error: %v", err)
+	}
+	if err := m.PublishSensorAttributes(sensor, map[string]interface{}{"measured_on": "now"}); err != nil {
+		t.Fatalf("PublishSensorAttributes() error: %v", err)
+	}
+
+	// Other status messages are ignored
+	fake.deliver(t, "homeassistant/status", "offline")
+
+	fake.deliver(t, "homeassistant/status", "online")
+	fake.waitForPublished(t, m.AttributesTopic(sensor), 2)
+
+	if got := len(fake.published(m.ConfigTopic(sensor))); got != 2 {
+		t.Errorf("config published %d times, want 2", got)
+	}
+	if got := len(fake.published(m.AvailabilityTopic())); got != 2 {
+		t.Errorf("availability published %d times, want 2", got)
+	}
+	states := fake.published(m.StateTopic(sensor))
+	if len(states) != 2 || states[1].Payload != "21.5" {
+		t.Errorf("states = %v, want last state 21.5 re-published", states)
+	}
+}
+
+func TestOfflineBufferReplay(t *testing.T) {
+	m, fake := newTestMQTTClient(testConfig())
+	m.buffer = NewOfflineBuffer(filepath.Join(t.TempDir(), "buffer.jsonl"), 10)
+	sensor := *GetSensorByQueryParam("tempf")
+
+	reading := func(state, measuredOn string) Reading {
+		return Reading{
+			ReceivedAt: time.Now(),
+			Values: []SensorValue{{
+				Sensor:     sensor,
+				State:      state,
+				Attributes: map[string]interface{}{"measured_on": measuredOn},
+			}},
+		}
+	}
+
+	// Disconnected: readings are buffered
+	for i, state := range []string{"20.0", "21.0"} {
+		if got := m.PublishReading(reading(state, fmt.Sprintf("2025-12-01T12:0%d:00Z", i))); got != 0 {
+			t.Errorf("PublishReading() while disconnected = %d, want 0", got)
+		}
+	}
+	if got := m.buffer.Len(); got != 2 {
+		t.Fatalf("buffered %d readings, want 2", got)
+	}
+	if got := fake.published(m.StateTopic(&sensor)); len(got) != 0 {
+		t.Fatalf("published %v while disconnected", got)
+	}
+
+	// Reconnecting replays them in order, before newer readings
+	m.onConnect(fake)
+	if got := m.PublishReading(reading("22.0", "2025-12-01T12:02:00Z")); got != 1 {
+		t.Errorf("PublishReading() = %d, want 1", got)
+	}
+
+	states := fake.published(m.StateTopic(&sensor))
+	attrs := fake.published(m.AttributesTopic(&sensor))
+	if len(states) != 3 || len(attrs) != 3 {
+		t.Fatalf("published %d states and %d attributes, want 3", len(states), len(attrs))
+	}
+	for i, want := range []string{"20.0", "21.0", "22.0"} {
+		if states[i].Payload != want {
+			t.Errorf("state %d = %q, want %q", i, states[i].Payload, want)
+		}
+		wantAttrs := fmt.Sprintf(`{"measured_on":"2025-12-01T12:0%d:00Z"}`, i)
+		if attrs[i].Payload != wantAttrs {
+			t.Errorf("attributes %d = %s, want %s", i, attrs[i].Payload, wantAttrs)
+		}
+	}
+	if got := m.buffer.Len(); got != 0 {
+		t.Errorf("buffer holds %d readings after replay, want 0", got)
+	}
+}
+
+// writeTestCertificate writes a self-signed certificate and its key as PEM
+// files and returns their paths.
+func writeTestCertificate(t *testing.T) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "broker.example.lan"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IsCA:         true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t)
+
+	t.Run("system roots", func(t *testing.T) {
+		tlsConfig, err := newTLSConfig(&BrokerConfig{})
+		if err != nil {
+			t.Fatalf("newTLSConfig() error: %v", err)
+		}
+		if tlsConfig.RootCAs != nil || len(tlsConfig.Certificates) != 0 || tlsConfig.InsecureSkipVerify {
+			t.Errorf("newTLSConfig() = %+v, want defaults", tlsConfig)
+		}
+	})
+
+	t.Run("CA and client certificate", func(t *testing.T) {
+		tlsConfig, err := newTLSConfig(&BrokerConfig{
+			CAFile:     certFile,
+			ClientCert: certFile,
+			ClientKey:  keyFile,
+			ServerName: "broker.example.lan",
+		})
+		if err != nil {
+			t.Fatalf("newTLSConfig() error: %v", err)
+		}
+		if tlsConfig.RootCAs == nil {
+			t.Error("RootCAs not set")
+		}
+		if len(tlsConfig.Certificates) != 1 {
+			t.Errorf("got %d client certificates, want 1", len(tlsConfig.Certificates))
+		}
+		if tlsConfig.ServerName != "broker.example.lan" {
+			t.Errorf("ServerName = %q", tlsConfig.ServerName)
+		}
+	})
+
+	errorCases := map[string]*BrokerConfig{
+		"missing CA file":  {CAFile: filepath.Join(t.TempDir(), "missing.pem")},
+		"CA without certs": {CAFile: keyFile},
+		"cert without key": {ClientCert: certFile},
+	}
+	for name, broker := range errorCases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := newTLSConfig(broker); err == nil {
+				t.Error("newTLSConfig() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestBrokerURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		broker   BrokerConfig
+		expected string
+	}{
+		{"host and port", BrokerConfig{Host: "core-mosquitto", Port: 1883}, "tcp://core-mosquitto:1883"},
+		{"host with TLS", BrokerConfig{Host: "broker.lan", Port: 8883, TLS: true}, "ssl://broker.lan:8883"},
+		{"IPv6 host", BrokerConfig{Host: "fd00::1", Port: 1883}, "tcp://[fd00::1]:1883"},
+		{"tcp URL", BrokerConfig{URL: "tcp://broker.lan:1884", Host: "ignored"}, "tcp://broker.lan:1884"},
+		{"ws URL with path", BrokerConfig{URL: "ws://proxy.lan:8080/mqtt"}, "ws://proxy.lan:8080/mqtt"},
+		{"wss URL", BrokerConfig{URL: "wss://mqtt.example.com/ws/mqtt"}, "wss://mqtt.example.com/ws/mqtt"},
+		{"tcp URL with TLS", BrokerConfig{URL: "tcp://broker.lan:8883", TLS: true}, "ssl://broker.lan:8883"},
+		{"ws URL with TLS", BrokerConfig{URL: "ws://proxy.lan:8443/mqtt", TLS: true}, "wss://proxy.lan:8443/mqtt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := brokerURL(&tt.broker)
+			if err != nil {
+				t.Fatalf("brokerURL() error: %v", err)
+			}
+			if got := u.String(); got != tt.expected {
+				t.Errorf("brokerURL() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+
+	for _, invalid := range []string{"http://broker.lan", "mqtt.example.com:1883", "ws:///mqtt", "tcp://%zz"} {
+		t.Run(invalid, func(t *testing.T) {
+			if _, err := brokerURL(&BrokerConfig{URL: invalid}); err == nil {
+				t.Errorf("brokerURL(%q) error = nil, want error", invalid)
+			}
+		})
+	}
+}
+
+func TestJSONStateMode(t *testing.T) {
+	cfg := testConfig()
+	cfg.MQTTStateMode = StateModeJSON
+	m, fake := newTestMQTTClient(cfg)
+	m.onConnect(fake)
+
+	temperature := *GetSensorByQueryParam("tempf")
+	published := m.PublishReading(Reading{
+		ReceivedAt: time.Now(),
+		Values: []SensorValue{
+			{Sensor: temperature, State: "21.5", Attributes: map[string]interface{}{"measured_on": "2025-12-01T12:00:00Z"}},
+			{Sensor: TextSensorDefinitions[0], State: "rising slowly", Attributes: map[string]interface{}{}},
+		},
+	})
+	if published != 2 {
+		t.Fatalf("PublishReading() = %d, want 2", published)
+	}
+
+	stateTopic := "homeassistant/sensor/weather_station/state"
+	states := fake.published(stateTopic)
+	if len(states) != 1 {
+		t.Fatalf("published %d state documents, want 1", len(states))
+	}
+	want := `{"attributes":{"pressure_trend":{},"temperature":{"measured_on":"2025-12-01T12:00:00Z"}},` +
+		`"pressure_trend":"rising slowly","temperature":21.5}`
+	if states[0].Payload != want {
+		t.Errorf("state document = %s, want %s", states[0].Payload, want)
+	}
+	if got := fake.published(m.AttributesTopic(&temperature)); len(got) != 0 {
+		t.Errorf("published %d attribute messages in JSON state mode, want 0", len(got))
+	}
+
+	configs := fake.published(m.ConfigTopic(&temperature))
+	if len(configs) != 1 {
+		t.Fatalf("published %d configs, want 1", len(configs))
+	}
+	var payload DiscoveryPayload
+	if err := json.Unmarshal([]byte(configs[0].Payload), &payload); err != nil {
+		t.Fatalf("invalid config payload: %v", err)
+	}
+	if payload.StateTopic != stateTopic || payload.JSONAttributesTopic != stateTopic {
+		t.Errorf("state topic = %q, attributes topic = %q, want %q", payload.StateTopic, payload.JSONAttributesTopic, stateTopic)
+	}
+	if payload.ValueTemplate != "{{ value_json.temperature if 'temperature' in value_json else none }}" {
+		t.Errorf("value_template = %q", payload.ValueTemplate)
+	}
+	wantAttrs := "{{ (value_json.attributes.temperature if 'temperature' in value_json.attributes else {}) | tojson }}"
+	if payload.JSONAttributesTemplate != wantAttrs {
+		t.Errorf("json_attributes_template = %q", payload.JSONAttributesTemplate)
+	}
+
+	// The document is re-published when Home Assistant comes back
+	m.republishStates()
+	if got := fake.published(stateTopic); len(got) != 2 || got[1].Payload != want {
+		t.Errorf("re-published documents = %v", got)
+	}
+}
+
+func TestStationAvailability(t *testing.T) {
+	cfg := testConfig()
+	cfg.StationTimeout = 10 * time.Minute
+	cfg.SensorExpireAfter = 15 * time.Minute
+	m, fake := newTestMQTTClient(cfg)
+
+	// The station is offline until the first update
+	m.onConnect(fake)
+	stationTopic := "homeassistant/sensor/weather_station/station"
+	if got := fake.published(stationTopic); len(got) != 1 || got[0].Payload != "offline" || !got[0].Retained {
+		t.Errorf("station messages = %+v, want retained offline", got)
+	}
+
+	m.SetStationOnline(true)
+	if got := fake.published(stationTopic); len(got) != 2 || got[1].Payload != "online" {
+		t.Errorf("station messages = %+v, want online", got)
+	}
+
+	data, err := m.discoveryPayload(GetSensorByQueryParam("tempf"))
+	if err != nil {
+		t.Fatalf("discoveryPayload() error: %v", err)
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+	if _, ok := payload["availability_topic"]; ok {
+		t.Error("availability_topic set together with availability list")
+	}
+	availability, _ := json.Marshal(payload["availability"])
+	want := `[{"topic":"homeassistant/sensor/weather_station/availability"},{"topic":"homeassistant/sensor/weather_station/station"}]`
+	if string(availability) != want {
+		t.Errorf("availability = %s, want %s", availability, want)
+	}
+	if payload["availability_mode"] != "all" {
+		t.Errorf("availability_mode = %v, want all", payload["availability_mode"])
+	}
+	if payload["expire_after"] != float64(900) {
+		t.Errorf("expire_after = %v, want 900", payload["expire_after"])
+	}
+}
+
+func TestStationAvailabilityDiagnostics(t *testing.T) {
+	cfg := testConfig()
+	cfg.StationTimeout = 10 * time.Minute
+	cfg.SensorExpireAfter = 15 * time.Minute
+	m, _ := newTestMQTTClient(cfg)
+
+	// Diagnostics stay available while the station is offline
+	for _, id := range []string{"last_packet", "parse_errors", "bridge_version"} {
+		data, err := m.discoveryPayload(GetSensorByID(id))
+		if err != nil {
+			t.Fatalf("discoveryPayload(%s) error: %v", id, err)
+		}
+		var payload map[string]interface{}
+		if err := json.Unmarshal(data, &payload); err != nil {
+			t.Fatalf("invalid payload: %v", err)
+		}
+		if got := payload["availability_topic"]; got != "homeassistant/sensor/weather_station/availability" {
+			t.Errorf("%s availability_topic = %v, want the bridge availability", id, got)
+		}
+		for _, key := range []string{"availability", "availability_mode", "expire_after"} {
+			if got, ok := payload[key]; ok {
+				t.Errorf("%s %s = %v, want unset", id, key, got)
+			}
+		}
+	}
+}
+
+func TestMQTT5StateProperties(t *testing.T) {
+	tests := []struct {
+		name          string
+		messageExpiry time.Duration
+		wantExpiry    time.Duration
+	}{
+		{"station timeout", 0, 10 * time.Minute},
+		{"configured", 2 * time.Minute, 2 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.StationTimeout = 10 * time.Minute
+			cfg.MQTTMessageExpiry = tt.messageExpiry
+			m, _ := newTestMQTTClient(cfg)
+			fake := &fakePropertyClient{fakeClient: &fakeClient{}}
+			m.client = fake
+			m.onConnect(fake)
+
+			temperature := *GetSensorByQueryParam("tempf")
+			m.PublishReading(Reading{
+				ReceivedAt: time.Now(),
+				Values: []SensorValue{
+					{Sensor: temperature, State: "21.5", Attributes: map[string]interface{}{"measured_on": "2025-12-01T12:00:00Z"}},
+				},
+			})
+
+			want := publishProperties{
+				MessageExpiry: tt.wantExpiry,
+				User:          map[string]string{"station_id": "weather_station", "measured_on": "2025-12-01T12:00:00Z"},
+			}
+			for _, topic := range []string{m.StateTopic(&temperature), m.AttributesTopic(&temperature)} {
+				got := fake.properties[topic]
+				if len(got) != 1 || !reflect.DeepEqual(got[0], want) {
+					t.Errorf("%s properties = %+v, want %+v", topic, got, want)
+				}
+			}
+
+			// Diagnostics do not expire
+			lastPacket := GetSensorByID("last_packet")
+			m.PublishReading(Reading{
+				ReceivedAt: time.Now(),
+				Values:     []SensorValue{{Sensor: *lastPacket, State: "2025-12-01T12:00:00Z", Attributes: map[string]interface{}{}}},
+			})
+			if got := fake.properties[m.StateTopic(lastPacket)]; len(got) != 1 || got[0].MessageExpiry != 0 {
+				t.Errorf("diagnostic state properties = %+v, want no expiry", got)
+			}
+
+			// Discovery configs and availability carry no properties
+			if got := fake.properties[m.ConfigTopic(&temperature)]; got != nil {
+				t.Errorf("config properties = %+v, want none", got)
+			}
+			if got := fake.properties[m.AvailabilityTopic()]; got != nil {
+				t.Errorf("availability properties = %+v, want none", got)
+			}
+		})
+	}
+}
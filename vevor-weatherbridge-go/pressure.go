@@ -0,0 +1,85 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+// Copyright (C) 2025 Lenucksi
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math"
+	"net/url"
+	"time"
+)
+
+const (
+	// tendencyWindow is the period the pressure tendency is reported for.
+	tendencyWindow = 3 * time.Hour
+	// minTendencySpan is the minimum history needed before reporting a
+	// tendency, allowing for the gap between station updates.
+	minTendencySpan = tendencyWindow - 10*time.Minute
+)
+
+// PressureTrend classifies a 3-hour pressure change in hPa using the
+// Met Office barometric tendency terms.
+func PressureTrend(deltaHPa float64) string {
+	magnitude := math.Abs(deltaHPa)
+	direction := "rising"
+	if deltaHPa < 0 {
+		direction = "falling"
+	}
+
+	switch {
+	case magnitude < 0.1:
+		return "steady"
+	case magnitude <= 1.5:
+		return direction + " slowly"
+	case magnitude <= 3.5:
+		return direction
+	case magnitude <= 6.0:
+		return direction + " quickly"
+	default:
+		return direction + " very rapidly"
+	}
+}
+
+// pressureTendency records the current relative pressure and returns the
+// change in hPa over the last three hours, if enough history is available.
+func (h *WeatherHandler) pressureTendency(params url.Values, now time.Time) (float64, bool) {
+	pressure, ok := paramFloat(params, "baromin")
+	if !ok {
+		return 0, false
+	}
+	h.history.Add("barometric_pressure", now, InHgToHPa(pressure))
+
+	samples := h.history.Since("barometric_pressure", now.Add(-tendencyWindow))
+	if len(samples) < 2 {
+		return 0, false
+	}
+
+	oldest, latest := samples[0], samples[len(samples)-1]
+	span := latest.At.Sub(oldest.At)
+	if span < minTendencySpan {
+		return 0, false
+	}
+
+	return roundTo(latest.Value-oldest.Value, 1), true
+}
+
+// pressureDelta converts a pressure change in hPa to the configured unit.
+func (h *WeatherHandler) pressureDelta(deltaHPa float64) float64 {
+	if h.cfg.IsMetric() {
+		return deltaHPa
+	}
+	return HPaToInHg(deltaHPa)
+}
@@ -0,0 +1,82 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+// Copyright (C) 2025 Lenucksi
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestPressureTrend(t *testing.T) {
+	tests := []struct {
+		delta    float64
+		expected string
+	}{
+		{0.0, "steady"},
+		{-0.05, "steady"},
+		{0.8, "rising slowly"},
+		{-1.5, "falling slowly"},
+		{2.0, "rising"},
+		{-3.5, "falling"},
+		{4.2, "rising quickly"},
+		{-6.0, "falling quickly"},
+		{7.5, "rising very rapidly"},
+		{-9.0, "falling very rapidly"},
+	}
+
+	for _, tt := range tests {
+		if got := PressureTrend(tt.delta); got != tt.expected {
+			t.Errorf("PressureTrend(%v) = %q, want %q", tt.delta, got, tt.expected)
+		}
+	}
+}
+
+func TestPressureTendency(t *testing.T) {
+	h := &WeatherHandler{
+		cfg:     &Config{Units: "metric"},
+		history: NewHistory(tendencyWindow + 15*time.Minute),
+	}
+	start := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+
+	// A single sample is not enough
+	if _, ok := h.pressureTendency(url.Values{"baromin": {"29.92"}}, start); ok {
+		t.Error("tendency reported with a single sample")
+	}
+
+	// Too short a history is not enough either, it is not extrapolated
+	if _, ok := h.pressureTendency(url.Values{"baromin": {"29.94"}}, start.Add(30*time.Minute)); ok {
+		t.Error("tendency reported with 30 minutes of history")
+	}
+	if _, ok := h.pressureTendency(url.Values{"baromin": {"29.98"}}, start.Add(2*time.Hour)); ok {
+		t.Error("tendency reported with two hours of history")
+	}
+
+	// 29.92 inHg -> 30.04 inHg over three hours is about +4.1 hPa
+	delta, ok := h.pressureTendency(url.Values{"baromin": {"30.04"}}, start.Add(3*time.Hour))
+	if !ok {
+		t.Fatal("no tendency reported after three hours")
+	}
+	if delta < 3.9 || delta > 4.3 {
+		t.Errorf("delta = %v, want about 4.1", delta)
+	}
+
+	// Missing pressure reports nothing
+	if _, ok := h.pressureTendency(url.Values{}, start.Add(3*time.Hour)); ok {
+		t.Error("tendency reported without pressure")
+	}
+}
@@ -0,0 +1,174 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+// Copyright (C) 2025 Lenucksi
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Drop policies applied when the publish queue is full.
+const (
+	// DropOldest discards the oldest queued reading to make room.
+	DropOldest = "oldest"
+	// DropNewest discards the incoming reading.
+	DropNewest = "newest"
+)
+
+// SensorValue is a formatted sensor state with its attributes.
+type SensorValue struct {
+	Sensor     SensorDefinition       `json:"sensor"`
+	State      string                 `json:"state"`
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+// Reading holds the sensor values of a single station update.
+type Reading struct {
+	ReceivedAt time.Time     `json:"received_at"`
+	Values     []SensorValue `json:"values"`
+}
+
+// Publisher publishes readings to a broker.
+type Publisher interface {
+	// PublishReading publishes all values of a reading and returns the
+	// number of sensors published.
+	PublishReading(reading Reading) int
+}
+
+// QueueStats holds the counters of a publish queue.
+type QueueStats struct {
+	Length    int
+	Published uint64
+	Dropped   uint64
+	Late      uint64
+}
+
+// PublishQueue decouples the HTTP handlers from the broker. Readings are
+// queued without blocking and published in order by a single worker, so a
+// slow broker never delays the response to the station.
+type PublishQueue struct {
+	publisher Publisher
+	policy    string
+	lateAfter time.Duration
+
+	readings chan Reading
+	done     chan struct{}
+
+	// mu serialises enqueueing with dropping and closing
+	mu     sync.Mutex
+	closed bool
+
+	published atomic.Uint64
+	dropped   atomic.Uint64
+	late      atomic.Uint64
+}
+
+// NewPublishQueue creates a queue holding up to size readings and starts its
+// worker. Readings published more than lateAfter after they were received
+// are counted as late.
+func NewPublishQueue(publisher Publisher, size int, policy string, lateAfter time.Duration) *PublishQueue {
+	q := &PublishQueue{
+		publisher: publisher,
+		policy:    policy,
+		lateAfter: lateAfter,
+		readings:  make(chan Reading, size),
+		done:      make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+// Enqueue queues a reading for publishing without blocking. When the queue is
+// full a reading is dropped according to the drop policy. Returns false if
+// the given reading was dropped.
+func (q *PublishQueue) Enqueue(reading Reading) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		q.dropped.Add(1)
+		return false
+	}
+
+	select {
+	case q.readings <- reading:
+		return true
+	default:
+	}
+
+	if q.policy == DropNewest {
+		q.dropped.Add(1)
+		slog.Warn("Publish queue full, dropping newest reading", "capacity", cap(q.readings))
+		return false
+	}
+
+	// Make room by discarding the oldest reading. The worker may have taken
+	// one in the meantime, in which case nothing needs to be dropped.
+	select {
+	case <-q.readings:
+		q.dropped.Add(1)
+		slog.Warn("Publish queue full, dropping oldest reading", "capacity", cap(q.readings))
+	default:
+	}
+	q.readings <- reading
+	return true
+}
+
+// run publishes queued readings until the queue is closed and drained.
+func (q *PublishQueue) run() {
+	defer close(q.done)
+
+	for reading := range q.readings {
+		if age := time.Since(reading.ReceivedAt); age > q.lateAfter {
+			q.late.Add(1)
+			slog.Warn("Publishing late reading", "age", age.Round(time.Millisecond))
+		}
+
+		count := q.publisher.PublishReading(reading)
+		q.published.Add(1)
+		slog.Debug("Published reading", "sensors_published", count, "queued", len(q.readings))
+	}
+}
+
+// Stats returns the current queue length and counters.
+func (q *PublishQueue) Stats() QueueStats {
+	return QueueStats{
+		Length:    len(q.readings),
+		Published: q.published.Load(),
+		Dropped:   q.dropped.Load(),
+		Late:      q.late.Load(),
+	}
+}
+
+// Close stops accepting readings and waits up to timeout for the queued ones
+// to be published.
+func (q *PublishQueue) Close(timeout time.Duration) {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.readings)
+	}
+	q.mu.Unlock()
+
+	select {
+	case <-q.done:
+	case <-time.After(timeout):
+		slog.Warn("Timeout draining publish queue", "remaining", len(q.readings))
+	}
+}
@@ -0,0 +1,134 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+// Copyright (C) 2025 Lenucksi
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePublisher records published readings, blocking until released.
+type fakePublisher struct {
+	release  chan struct{}
+	readings []Reading
+	mu       sync.Mutex
+}
+
+func newFakePublisher() *fakePublisher {
+	return &fakePublisher{release: make(chan struct{})}
+}
+
+func (p *fakePublisher) PublishReading(reading Reading) int {
+	<-p.release
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.readings = append(p.readings, reading)
+	return len(reading.Values)
+}
+
+// received returns the ReceivedAt seconds of the published readings.
+func (p *fakePublisher) received() []int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var seconds []int
+	for _, r := range p.readings {
+		seconds = append(seconds, r.ReceivedAt.Second())
+	}
+	return seconds
+}
+
+// readingAt returns an empty reading received at the given second.
+func readingAt(second int) Reading {
+	return Reading{ReceivedAt: time.Now().Truncate(time.Minute).Add(time.Duration(second) * time.Second)}
+}
+
+func TestPublishQueueDropPolicy(t *testing.T) {
+	tests := []struct {
+		policy   string
+		expected []int
+	}{
+		// Reading 0 is taken by the worker, 1 and 2 fill the queue
+		{DropOldest, []int{0, 2, 3}},
+		{DropNewest, []int{0, 1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			pub := newFakePublisher()
+			q := NewPublishQueue(pub, 2, tt.policy, time.Hour)
+
+			q.Enqueue(readingAt(0))
+			// Wait for the worker to pick up the first reading
+			for q.Stats().Length != 0 {
+				time.Sleep(time.Millisecond)
+			}
+			for i := 1; i <= 3; i++ {
+				q.Enqueue(readingAt(i))
+			}
+
+			if got := q.Stats().Dropped; got != 1 {
+				t.Errorf("Dropped = %d, want 1", got)
+			}
+
+			close(pub.release)
+			q.Close(time.Second)
+
+			got := pub.received()
+			if len(got) != len(tt.expected) {
+				t.Fatalf("published %v, want %v", got, tt.expected)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Fatalf("published %v, want %v", got, tt.expected)
+				}
+			}
+			if stats := q.Stats(); stats.Published != 3 {
+				t.Errorf("Published = %d, want 3", stats.Published)
+			}
+		})
+	}
+}
+
+func TestPublishQueueLateReadings(t *testing.T) {
+	pub := newFakePublisher()
+	close(pub.release)
+	q := NewPublishQueue(pub, 10, DropOldest, time.Minute)
+
+	q.Enqueue(Reading{ReceivedAt: time.Now()})
+	q.Enqueue(Reading{ReceivedAt: time.Now().Add(-2 * time.Minute)})
+	q.Close(time.Second)
+
+	stats := q.Stats()
+	if stats.Published != 2 || stats.Late != 1 {
+		t.Errorf("Stats() = %+v, want 2 published and 1 late", stats)
+	}
+}
+
+func TestPublishQueueClosed(t *testing.T) {
+	pub := newFakePublisher()
+	close(pub.release)
+	q := NewPublishQueue(pub, 10, DropOldest, time.Minute)
+	q.Close(time.Second)
+
+	if q.Enqueue(Reading{ReceivedAt: time.Now()}) {
+		t.Error("Enqueue() after Close() = true, want false")
+	}
+	if got := q.Stats().Dropped; got != 1 {
+		t.Errorf("Dropped = %d, want 1", got)
+	}
+}
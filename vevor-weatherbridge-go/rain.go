@@ -0,0 +1,221 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+// Copyright (C) 2025 Lenucksi
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// rainRateWindow is the period the derived rain rate is averaged over.
+	rainRateWindow = 10 * time.Minute
+	// rainRecentWindow is how long rain increments are kept for rolling totals.
+	rainRecentWindow = 24 * time.Hour
+)
+
+// RainTotals contains rainfall values derived by the bridge, in inches
+// (rate in inches per hour).
+type RainTotals struct {
+	Rate     float64
+	LastHour float64
+	Last24h  float64
+	Weekly   float64
+	Monthly  float64
+	Yearly   float64
+}
+
+// rainState is the persisted state of the rain accumulator.
+type rainState struct {
+	LastDaily *float64 `json:"last_daily,omitempty"` // Last daily rain counter in inches
+	Week      string   `json:"week"`
+	Weekly    float64  `json:"weekly"`
+	Month     string   `json:"month"`
+	Monthly   float64  `json:"monthly"`
+	Year      string   `json:"year"`
+	Yearly    float64  `json:"yearly"`
+	Recent    []Sample `json:"recent"` // Rain increments within the recent window
+}
+
+// RainAccumulator derives rain rate and accumulation periods from successive
+// daily rain counter values. Totals are persisted so a restart does not
+// reset them.
+type RainAccumulator struct {
+	path  string
+	state rainState
+	mu    sync.Mutex
+}
+
+// NewRainAccumulator creates a rain accumulator persisting to path, restoring
+// previously saved totals if present.
+func NewRainAccumulator(path string) *RainAccumulator {
+	r := &RainAccumulator{path: path}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		// First start, nothing to restore
+	case err != nil:
+		slog.Warn("Failed to read rain totals", "path", path, "error", err)
+	default:
+		if err := json.Unmarshal(data, &r.state); err != nil {
+			slog.Warn("Failed to parse rain totals, starting from zero", "path", path, "error", err)
+			r.state = rainState{}
+		} else {
+			slog.Info("Restored rain totals", "path", path)
+		}
+	}
+
+	return r
+}
+
+// Add records a daily rain counter value in inches at the given local time
+// and returns the derived totals. A counter lower than the previous value is
+// treated as a reset by the station.
+func (r *RainAccumulator) Add(now time.Time, daily float64) RainTotals {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Only save when the state changed, most updates report the same counter
+	changed := r.state.LastDaily == nil || *r.state.LastDaily != daily
+	periods := [3]string{r.state.Week, r.state.Month, r.state.Year}
+
+	increment := 0.0
+	if last := r.state.LastDaily; last != nil {
+		if daily >= *last {
+			increment = daily - *last
+		} else {
+			// Counter reset: everything counted since the reset is new rain
+			increment = daily
+		}
+	}
+	r.state.LastDaily = &daily
+
+	// Start new periods as the calendar moves on
+	year, week := now.ISOWeek()
+	r.state.Weekly = resetPeriod(&r.state.Week, fmt.Sprintf("%d-W%02d", year, week), r.state.Weekly)
+	r.state.Monthly = resetPeriod(&r.state.Month, now.Format("2006-01"), r.state.Monthly)
+	r.state.Yearly = resetPeriod(&r.state.Year, now.Format("2006"), r.state.Yearly)
+	if periods != [3]string{r.state.Week, r.state.Month, r.state.Year} {
+		changed = true
+	}
+
+	r.state.Weekly += increment
+	r.state.Monthly += increment
+	r.state.Yearly += increment
+
+	// Keep increments for the rolling windows
+	if increment > 0 {
+		r.state.Recent = append(r.state.Recent, Sample{At: now, Value: increment})
+	}
+	cutoff := now.Add(-rainRecentWindow)
+	first := 0
+	for first < len(r.state.Recent) && r.state.Recent[first].At.Before(cutoff) {
+		first++
+	}
+	r.state.Recent = r.state.Recent[first:]
+
+	if changed {
+		if err := r.save(); err != nil {
+			slog.Warn("Failed to save rain totals", "path", r.path, "error", err)
+		}
+	}
+
+	return RainTotals{
+		Rate:     r.recentSum(now.Add(-rainRateWindow)) * float64(time.Hour) / float64(rainRateWindow),
+		LastHour: r.recentSum(now.Add(-time.Hour)),
+		Last24h:  r.recentSum(cutoff),
+		Weekly:   r.state.Weekly,
+		Monthly:  r.state.Monthly,
+		Yearly:   r.state.Yearly,
+	}
+}
+
+// resetPeriod returns zero and stores the new period key if it differs from
+// the current one, otherwise it returns total unchanged.
+func resetPeriod(current *string, key string, total float64) float64 {
+	if *current != key {
+		*current = key
+		return 0
+	}
+	return total
+}
+
+// recentSum returns the sum of rain increments after since.
+func (r *RainAccumulator) recentSum(since time.Time) float64 {
+	sum := 0.0
+	for _, s := range r.state.Recent {
+		if s.At.After(since) {
+			sum += s.Value
+		}
+	}
+	return sum
+}
+
+// save writes the state to disk atomically.
+func (r *RainAccumulator) save() error {
+	data, err := json.Marshal(r.state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal rain totals: %w", err)
+	}
+
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write rain totals: %w", err)
+	}
+	if err := os.Rename(tmp, r.path); err != nil {
+		return fmt.Errorf("failed to replace rain totals: %w", err)
+	}
+	return nil
+}
+
+// rainTotals records the daily rain counter and adds derived rain rate and
+// accumulation periods to params. Values sent by the station win.
+func (h *WeatherHandler) rainTotals(params url.Values, now time.Time) {
+	daily, ok := paramFloat(params, "dailyrainin")
+	if !ok || h.rain == nil {
+		return
+	}
+
+	totals := h.rain.Add(now.In(h.cfg.Timezone), daily)
+	derived := map[string]float64{
+		"rainratein":    totals.Rate,
+		"rainin":        totals.LastHour,
+		"rain24hin":     totals.Last24h,
+		"weeklyrainin":  totals.Weekly,
+		"monthlyrainin": totals.Monthly,
+		"yearlyrainin":  totals.Yearly,
+	}
+	for param, value := range derived {
+		if !params.Has(param) {
+			setDerived(params, param, value)
+		}
+	}
+}
+
+// rainStatePath returns the path of the persisted rain totals.
+func rainStatePath(cfg *Config) string {
+	return filepath.Join(cfg.DataDir, "rain.json")
+}
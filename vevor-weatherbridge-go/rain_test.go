@@ -0,0 +1,156 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+// Copyright (C) 2025 Lenucksi
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRainAccumulator(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rain.json")
+	r := NewRainAccumulator(path)
+	start := time.Date(2025, 12, 1, 10, 0, 0, 0, time.UTC) // Monday
+
+	// First value only establishes the baseline
+	totals := r.Add(start, 0.10)
+	if totals.Weekly != 0 || totals.LastHour != 0 {
+		t.Errorf("first update totals = %+v, want zero", totals)
+	}
+
+	r.Add(start.Add(5*time.Minute), 0.15)
+	totals = r.Add(start.Add(10*time.Minute), 0.25)
+
+	assertClose(t, "Weekly", totals.Weekly, 0.15)
+	assertClose(t, "Monthly", totals.Monthly, 0.15)
+	assertClose(t, "Yearly", totals.Yearly, 0.15)
+	assertClose(t, "LastHour", totals.LastHour, 0.15)
+	assertClose(t, "Last24h", totals.Last24h, 0.15)
+	// 0.15 in within the last 10 minutes is 0.9 in/h
+	assertClose(t, "Rate", totals.Rate, 0.9)
+
+	// An hour later without rain
+	totals = r.Add(start.Add(75*time.Minute), 0.25)
+	assertClose(t, "Rate", totals.Rate, 0)
+	assertClose(t, "LastHour", totals.LastHour, 0)
+	assertClose(t, "Last24h", totals.Last24h, 0.15)
+}
+
+func TestRainAccumulatorCounterReset(t *testing.T) {
+	r := NewRainAccumulator(filepath.Join(t.TempDir(), "rain.json"))
+	day := time.Date(2025, 12, 1, 23, 50, 0, 0, time.UTC)
+
+	r.Add(day, 0.50)
+	// Station resets its daily counter and has already counted new rain
+	totals := r.Add(day.Add(20*time.Minute), 0.05)
+	assertClose(t, "Weekly", totals.Weekly, 0.05)
+}
+
+func TestRainAccumulatorPeriods(t *testing.T) {
+	r := NewRainAccumulator(filepath.Join(t.TempDir(), "rain.json"))
+	sunday := time.Date(2025, 11, 30, 12, 0, 0, 0, time.UTC)
+
+	r.Add(sunday, 0)
+	r.Add(sunday.Add(time.Hour), 0.20)
+
+	// Monday starts a new week and a new month, but not a new year
+	monday := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+	totals := r.Add(monday, 0.10)
+	assertClose(t, "Weekly", totals.Weekly, 0.10)
+	assertClose(t, "Monthly", totals.Monthly, 0.10)
+	assertClose(t, "Yearly", totals.Yearly, 0.30)
+}
+
+func TestRainAccumulatorPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rain.json")
+	start := time.Date(2025, 12, 1, 10, 0, 0, 0, time.UTC)
+
+	r := NewRainAccumulator(path)
+	r.Add(start, 0)
+	r.Add(start.Add(5*time.Minute), 0.30)
+
+	// A restarted bridge continues from the saved totals
+	restarted := NewRainAccumulator(path)
+	totals := restarted.Add(start.Add(10*time.Minute), 0.40)
+	assertClose(t, "Weekly", totals.Weekly, 0.40)
+	assertClose(t, "Last24h", totals.Last24h, 0.40)
+}
+
+func TestRainAccumulatorSavesOnlyChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rain.json")
+	start := time.Date(2025, 12, 1, 10, 0, 0, 0, time.UTC)
+
+	r := NewRainAccumulator(path)
+	r.Add(start, 0.10)
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("state not saved: %v", err)
+	}
+
+	// An unchanged counter within the same periods is not saved
+	r.Add(start.Add(time.Minute), 0.10)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("state saved for an unchanged counter: %v", err)
+	}
+
+	// A new month is
+	r.Add(time.Date(2026, 1, 1, 0, 5, 0, 0, time.UTC), 0.10)
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("state not saved for a new month: %v", err)
+	}
+}
+
+func TestRainAccumulatorCorruptState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rain.json")
+	if err := os.WriteFile(path, []byte("not json"), 0o600); err != nil {
+		t.Fatalf("Failed to write state: %v", err)
+	}
+
+	r := NewRainAccumulator(path)
+	totals := r.Add(time.Now(), 0.10)
+	if totals.Yearly != 0 {
+		t.Errorf("Yearly = %v, want 0 after corrupt state", totals.Yearly)
+	}
+}
+
+func TestRainTotalsStationValuesWin(t *testing.T) {
+	h := &WeatherHandler{
+		cfg:  &Config{Timezone: time.UTC},
+		rain: NewRainAccumulator(filepath.Join(t.TempDir(), "rain.json")),
+	}
+
+	params := url.Values{"dailyrainin": {"0.10"}, "weeklyrainin": {"1.00"}}
+	h.rainTotals(params, time.Now())
+	if got := params.Get("weeklyrainin"); got != "1.00" {
+		t.Errorf("weeklyrainin = %q, want station value 1.00", got)
+	}
+	for _, param := range []string{"rainratein", "rainin", "rain24hin", "monthlyrainin", "yearlyrainin"} {
+		if !params.Has(param) {
+			t.Errorf("%s not derived", param)
+		}
+	}
+}
+
+func assertClose(t *testing.T, name string, got, want float64) {
+	t.Helper()
+	if math.Abs(got-want) > 1e-9 {
+		t.Errorf("%s = %v, want %v", name, got, want)
+	}
+}
@@ -28,8 +28,52 @@ bashio::log.info "Starting VEVOR Weather Station Bridge (Go)..."
 export DEVICE_NAME=$(bashio::config 'device_name')
 export DEVICE_MANUFACTURER=$(bashio::config 'device_manufacturer')
 export DEVICE_MODEL=$(bashio::config 'device_model')
+export DEVICE_SUGGESTED_AREA=$(bashio::config 'device_suggested_area')
+
+# The bridge device links to its add-on page unless configured otherwise
+if bashio::config.has_value 'configuration_url'; then
+    export CONFIGURATION_URL=$(bashio::config 'configuration_url')
+else
+    export CONFIGURATION_URL="homeassistant://hassio/addon/$(bashio::addon.slug)/info"
+fi
 export UNITS=$(bashio::config 'units')
+export CHANNEL_NAMES=$(bashio::config 'channel_names')
+export STATION_ELEVATION=$(bashio::config 'station_elevation')
+export HEMISPHERE=$(bashio::config 'hemisphere')
+export FROST_THRESHOLD=$(bashio::config 'frost_threshold')
+export HIGH_WIND_THRESHOLD=$(bashio::config 'high_wind_threshold')
+export DAYLIGHT_THRESHOLD=$(bashio::config 'daylight_threshold')
+export SENSOR_EXPIRE_AFTER=$(bashio::config 'sensor_expire_after')
+export STATION_TIMEOUT=$(bashio::config 'station_timeout')
 export MQTT_PREFIX=$(bashio::config 'mqtt_prefix')
+export MQTT_QOS=$(bashio::config 'mqtt_qos')
+export MQTT_VERSION=$(bashio::config 'mqtt_version')
+export MQTT_MESSAGE_EXPIRY=$(bashio::config 'mqtt_message_expiry')
+export MQTT_STATE_MODE=$(bashio::config 'mqtt_state_mode')
+
+# Optional per message kind topic template, QoS and retain overrides
+for kind in config state attributes availability station; do
+    for setting in topic qos retain; do
+        option="mqtt_${kind}_${setting}"
+        if bashio::config.has_value "${option}"; then
+            export "${option^^}=$(bashio::config "${option}")"
+        fi
+    done
+done
+
+export MQTT_TLS=$(bashio::config 'mqtt_tls')
+export MQTT_CA_FILE=$(bashio::config 'mqtt_ca_file')
+export MQTT_CLIENT_CERT=$(bashio::config 'mqtt_client_cert')
+export MQTT_CLIENT_KEY=$(bashio::config 'mqtt_client_key')
+export MQTT_SERVER_NAME=$(bashio::config 'mqtt_server_name')
+export MQTT_TLS_INSECURE=$(bashio::config 'mqtt_tls_insecure')
+export MQTT_QUEUE_SIZE=$(bashio::config 'mqtt_queue_size')
+export MQTT_DROP_POLICY=$(bashio::config 'mqtt_drop_policy')
+export MQTT_LATE_AFTER=$(bashio::config 'mqtt_late_after')
+export MQTT_BUFFER_SIZE=$(bashio::config 'mqtt_buffer_size')
+
+# Additional brokers are passed on as a JSON list
+export MQTT_EXTRA_BROKERS=$(jq -c '.mqtt_extra_brokers // []' /data/options.json)
 export TZ=$(bashio::config 'timezone')
 export LOG_LEVEL=$(bashio::config 'log_level')
 
@@ -48,12 +92,19 @@ fi
 
 # MQTT Configuration
 # Check if user provided manual MQTT configuration
+CONFIGURED_URL=$(bashio::config 'mqtt_url')
 CONFIGURED_HOST=$(bashio::config 'mqtt_host')
 CONFIGURED_PORT=$(bashio::config 'mqtt_port')
 CONFIGURED_USER=$(bashio::config 'mqtt_user')
 CONFIGURED_PASSWORD=$(bashio::config 'mqtt_password')
 
-if bashio::var.has_value "${CONFIGURED_HOST}"; then
+if bashio::var.has_value "${CONFIGURED_URL}"; then
+    # Use user-provided broker URL (tcp, ssl, ws or wss)
+    bashio::log.info "Using manually configured MQTT broker URL"
+    export MQTT_URL="${CONFIGURED_URL}"
+    export MQTT_USER="${CONFIGURED_USER}"
+    export MQTT_PASSWORD="${CONFIGURED_PASSWORD}"
+elif bashio::var.has_value "${CONFIGURED_HOST}"; then
     # Use user-provided MQTT configuration
     bashio::log.info "Using manually configured MQTT broker"
     export MQTT_HOST="${CONFIGURED_HOST}"
@@ -94,7 +145,14 @@ else
 fi
 
 bashio::log.info "Device: ${DEVICE_NAME} (${DEVICE_ID})"
-bashio::log.info "MQTT Broker: ${MQTT_HOST}:${MQTT_PORT}"
+if bashio::var.has_value "${MQTT_URL}"; then
+    # Only log scheme and host, the URL may carry credentials
+    mqtt_address="${MQTT_URL#*://}"
+    mqtt_address="${mqtt_address%%/*}"
+    bashio::log.info "MQTT Broker: ${MQTT_URL%%://*}://${mqtt_address##*@}"
+else
+    bashio::log.info "MQTT Broker: ${MQTT_HOST}:${MQTT_PORT}"
+fi
 bashio::log.info "Units: ${UNITS}"
 bashio::log.info "Timezone: ${TZ}"
 
@@ -16,6 +16,8 @@
 
 package main
 
+import "fmt"
+
 // SensorDefinition contains metadata for a weather sensor.
 type SensorDefinition struct {
 	Name         string  // Human-readable name (e.g., "Temperature")
@@ -27,8 +29,24 @@ type SensorDefinition struct {
 	Icon         string  // Material Design Icon (mdi:xxx), empty if device_class provides one
 	Precision    int     // Suggested display precision (0 = not set)
 	StateClass   string  // Home Assistant state_class ("measurement", "total", "total_increasing")
+	ChannelGroup string  // Channel naming group for extra sensors ("temp", "soil", "leaf")
+	Channel      int     // Extra sensor channel number (0 for the station's own sensors)
+
+	EntityCategory string // Home Assistant entity_category ("diagnostic"), empty for regular sensors
+	Bridge         bool   // Belongs to the bridge device instead of the station
+	Component      string // Home Assistant component, ComponentSensor if empty
 }
 
+// Home Assistant components of the published entities.
+const (
+	ComponentSensor       = "sensor"
+	ComponentBinarySensor = "binary_sensor"
+)
+
+// EntityCategoryDiagnostic marks sensors about the station and bridge rather
+// than the weather.
+const EntityCategoryDiagnostic = "diagnostic"
+
 // Helper to create a string pointer
 func strPtr(s string) *string {
 	return &s
@@ -46,6 +64,16 @@ var SensorDefinitions = []SensorDefinition{
 		StateClass:   "measurement",
 		Precision:    1,
 	},
+	{
+		Name:         "Absolute Barometric Pressure",
+		ID:           "absolute_pressure",
+		QueryParam:   "absbaromin",
+		DeviceClass:  strPtr("atmospheric_pressure"),
+		MetricUnit:   "hPa",
+		ImperialUnit: "inHg",
+		StateClass:   "measurement",
+		Precision:    1,
+	},
 	{
 		Name:         "Temperature",
 		ID:           "temperature",
@@ -76,6 +104,66 @@ var SensorDefinitions = []SensorDefinition{
 		StateClass:   "measurement",
 		Precision:    1,
 	},
+	{
+		Name:         "Heat Index",
+		ID:           "heat_index",
+		QueryParam:   "heatindexf", // Derived by the bridge unless sent by the station
+		DeviceClass:  strPtr("temperature"),
+		MetricUnit:   "°C",
+		ImperialUnit: "°F",
+		StateClass:   "measurement",
+		Precision:    1,
+	},
+	{
+		Name:         "Wind Chill",
+		ID:           "wind_chill",
+		QueryParam:   "windchillf", // Derived by the bridge unless sent by the station
+		DeviceClass:  strPtr("temperature"),
+		MetricUnit:   "°C",
+		ImperialUnit: "°F",
+		StateClass:   "measurement",
+		Precision:    1,
+	},
+	{
+		Name:         "Apparent Temperature",
+		ID:           "apparent_temperature",
+		QueryParam:   "apparenttempf", // Derived by the bridge
+		DeviceClass:  strPtr("temperature"),
+		MetricUnit:   "°C",
+		ImperialUnit: "°F",
+		StateClass:   "measurement",
+		Precision:    1,
+	},
+	{
+		Name:         "Indoor Temperature",
+		ID:           "indoor_temperature",
+		QueryParam:   "indoortempf",
+		DeviceClass:  strPtr("temperature"),
+		MetricUnit:   "°C",
+		ImperialUnit: "°F",
+		StateClass:   "measurement",
+		Precision:    1,
+	},
+	{
+		Name:         "Indoor Humidity",
+		ID:           "indoor_humidity",
+		QueryParam:   "indoorhumidity",
+		DeviceClass:  strPtr("humidity"),
+		MetricUnit:   "%",
+		ImperialUnit: "%",
+		StateClass:   "measurement",
+		Precision:    0,
+	},
+	{
+		Name:         "Indoor Dew Point",
+		ID:           "indoor_dew_point",
+		QueryParam:   "indoordewptf", // Derived by the bridge unless sent by the station
+		DeviceClass:  strPtr("temperature"),
+		MetricUnit:   "°C",
+		ImperialUnit: "°F",
+		StateClass:   "measurement",
+		Precision:    1,
+	},
 	{
 		Name:         "Rainfall",
 		ID:           "rainfall",
@@ -96,6 +184,66 @@ var SensorDefinitions = []SensorDefinition{
 		StateClass:   "total_increasing",
 		Precision:    1,
 	},
+	{
+		Name:         "Rain Rate",
+		ID:           "rain_rate",
+		QueryParam:   "rainratein",
+		DeviceClass:  strPtr("precipitation_intensity"),
+		MetricUnit:   "mm/h",
+		ImperialUnit: "in/h",
+		StateClass:   "measurement",
+		Precision:    1,
+	},
+	{
+		Name:         "Event Rainfall",
+		ID:           "event_rainfall",
+		QueryParam:   "eventrainin",
+		DeviceClass:  strPtr("precipitation"),
+		MetricUnit:   "mm",
+		ImperialUnit: "in",
+		StateClass:   "total_increasing",
+		Precision:    1,
+	},
+	{
+		Name:         "Rainfall 24h",
+		ID:           "rainfall_24h",
+		QueryParam:   "rain24hin", // Derived by the bridge unless sent by the station
+		DeviceClass:  strPtr("precipitation"),
+		MetricUnit:   "mm",
+		ImperialUnit: "in",
+		StateClass:   "measurement",
+		Precision:    1,
+	},
+	{
+		Name:         "Weekly Rainfall",
+		ID:           "weekly_rainfall",
+		QueryParam:   "weeklyrainin",
+		DeviceClass:  strPtr("precipitation"),
+		MetricUnit:   "mm",
+		ImperialUnit: "in",
+		StateClass:   "total_increasing",
+		Precision:    1,
+	},
+	{
+		Name:         "Monthly Rainfall",
+		ID:           "monthly_rainfall",
+		QueryParam:   "monthlyrainin",
+		DeviceClass:  strPtr("precipitation"),
+		MetricUnit:   "mm",
+		ImperialUnit: "in",
+		StateClass:   "total_increasing",
+		Precision:    1,
+	},
+	{
+		Name:         "Yearly Rainfall",
+		ID:           "yearly_rainfall",
+		QueryParam:   "yearlyrainin",
+		DeviceClass:  strPtr("precipitation"),
+		MetricUnit:   "mm",
+		ImperialUnit: "in",
+		StateClass:   "total_increasing",
+		Precision:    1,
+	},
 	{
 		Name:         "Wind Direction",
 		ID:           "wind_direction",
@@ -127,6 +275,78 @@ var SensorDefinitions = []SensorDefinition{
 		StateClass:   "measurement",
 		Precision:    1,
 	},
+	{
+		Name:         "Wind Speed 2 min Average",
+		ID:           "wind_speed_avg_2m",
+		QueryParam:   "windspdmph_avg2m", // Derived by the bridge unless sent by the station
+		DeviceClass:  strPtr("wind_speed"),
+		MetricUnit:   "km/h",
+		ImperialUnit: "mph",
+		StateClass:   "measurement",
+		Precision:    1,
+	},
+	{
+		Name:         "Wind Direction 2 min Average",
+		ID:           "wind_direction_avg_2m",
+		QueryParam:   "winddir_avg2m", // Derived by the bridge unless sent by the station
+		DeviceClass:  nil,
+		MetricUnit:   "°",
+		ImperialUnit: "°",
+		Icon:         "mdi:compass-outline",
+		StateClass:   "measurement",
+		Precision:    0,
+	},
+	{
+		Name:         "Wind Speed 10 min Average",
+		ID:           "wind_speed_avg_10m",
+		QueryParam:   "windspdmph_avg10m", // Derived by the bridge unless sent by the station
+		DeviceClass:  strPtr("wind_speed"),
+		MetricUnit:   "km/h",
+		ImperialUnit: "mph",
+		StateClass:   "measurement",
+		Precision:    1,
+	},
+	{
+		Name:         "Wind Direction 10 min Average",
+		ID:           "wind_direction_avg_10m",
+		QueryParam:   "winddir_avg10m", // Derived by the bridge unless sent by the station
+		DeviceClass:  nil,
+		MetricUnit:   "°",
+		ImperialUnit: "°",
+		Icon:         "mdi:compass-outline",
+		StateClass:   "measurement",
+		Precision:    0,
+	},
+	{
+		Name:         "Wind Gust 10 min Max",
+		ID:           "wind_gust_max_10m",
+		QueryParam:   "windgustmph_10m", // Derived by the bridge unless sent by the station
+		DeviceClass:  strPtr("wind_speed"),
+		MetricUnit:   "km/h",
+		ImperialUnit: "mph",
+		StateClass:   "measurement",
+		Precision:    1,
+	},
+	{
+		Name:         "Wind Gust Hourly Max",
+		ID:           "wind_gust_max_1h",
+		QueryParam:   "windgustmph_1h", // Derived by the bridge
+		DeviceClass:  strPtr("wind_speed"),
+		MetricUnit:   "km/h",
+		ImperialUnit: "mph",
+		StateClass:   "measurement",
+		Precision:    1,
+	},
+	{
+		Name:         "Wind Gust Daily Max",
+		ID:           "wind_gust_max_daily",
+		QueryParam:   "maxdailygust", // Derived by the bridge unless sent by the station
+		DeviceClass:  strPtr("wind_speed"),
+		MetricUnit:   "km/h",
+		ImperialUnit: "mph",
+		StateClass:   "measurement",
+		Precision:    1,
+	},
 	{
 		Name:         "UV Index",
 		ID:           "uv_index",
@@ -147,6 +367,293 @@ var SensorDefinitions = []SensorDefinition{
 		StateClass:   "measurement",
 		Precision:    1,
 	},
+	{
+		Name:         "Outdoor Sensor Battery",
+		ID:           "outdoor_battery",
+		QueryParam:   "battout",
+		DeviceClass:  nil,
+		MetricUnit:   "",
+		ImperialUnit: "",
+		Icon:         "mdi:battery",
+		StateClass:   "measurement",
+		Precision:    0,
+	},
+	{
+		Name:         "Indoor Sensor Battery",
+		ID:           "indoor_battery",
+		QueryParam:   "battin",
+		DeviceClass:  nil,
+		MetricUnit:   "",
+		ImperialUnit: "",
+		Icon:         "mdi:battery",
+		StateClass:   "measurement",
+		Precision:    0,
+	},
+	{
+		Name:         "PM2.5 Sensor Battery",
+		ID:           "pm25_battery",
+		QueryParam:   "batt_25",
+		DeviceClass:  nil,
+		MetricUnit:   "",
+		ImperialUnit: "",
+		Icon:         "mdi:battery",
+		StateClass:   "measurement",
+		Precision:    0,
+	},
+	{
+		Name:         "Lightning Sensor Battery",
+		ID:           "lightning_battery",
+		QueryParam:   "batt_lightning",
+		DeviceClass:  nil,
+		MetricUnit:   "",
+		ImperialUnit: "",
+		Icon:         "mdi:battery",
+		StateClass:   "measurement",
+		Precision:    0,
+	},
+}
+
+// TextSensorDefinitions contains sensors with non-numeric states computed by
+// the bridge. They have no query parameter, unit or state class.
+var TextSensorDefinitions = []SensorDefinition{
+	{
+		Name: "Pressure Trend",
+		ID:   "pressure_trend",
+		Icon: "mdi:chart-line-variant",
+	},
+	{
+		Name: "Forecast",
+		ID:   "forecast",
+		Icon: "mdi:weather-partly-cloudy",
+	},
+}
+
+// DiagnosticSensorDefinitions contains sensors about the station and the
+// bridge itself, shown as diagnostic entities. The bridge's own sensors belong
+// to the bridge device.
+var DiagnosticSensorDefinitions = []SensorDefinition{
+	{
+		Name:           "Last Packet",
+		ID:             "last_packet",
+		DeviceClass:    strPtr("timestamp"),
+		EntityCategory: EntityCategoryDiagnostic,
+	},
+	{
+		Name:           "Packets per Hour",
+		ID:             "packets_per_hour",
+		MetricUnit:     "packets/h",
+		ImperialUnit:   "packets/h",
+		Icon:           "mdi:counter",
+		StateClass:     "measurement",
+		EntityCategory: EntityCategoryDiagnostic,
+	},
+	{
+		Name:           "Parse Errors",
+		ID:             "parse_errors",
+		Icon:           "mdi:alert-circle-outline",
+		StateClass:     "total_increasing",
+		EntityCategory: EntityCategoryDiagnostic,
+	},
+	{
+		Name:           "Station Software",
+		ID:             "station_software",
+		QueryParam:     "softwaretype",
+		Icon:           "mdi:chip",
+		EntityCategory: EntityCategoryDiagnostic,
+	},
+	{
+		Name:           "Update Interval",
+		ID:             "update_interval",
+		QueryParam:     "rtfreq",
+		DeviceClass:    strPtr("duration"),
+		MetricUnit:     "s",
+		ImperialUnit:   "s",
+		StateClass:     "measurement",
+		EntityCategory: EntityCategoryDiagnostic,
+	},
+	{
+		Name:           "Bridge Version",
+		ID:             "bridge_version",
+		Icon:           "mdi:information-outline",
+		EntityCategory: EntityCategoryDiagnostic,
+		Bridge:         true,
+	},
+}
+
+// BinarySensorDefinitions contains on/off sensors for weather conditions
+// computed by the bridge from the configured thresholds. Their state is "ON"
+// or "OFF".
+var BinarySensorDefinitions = []SensorDefinition{
+	{
+		Name:        "Raining",
+		ID:          "raining",
+		DeviceClass: strPtr("moisture"),
+		Component:   ComponentBinarySensor,
+	},
+	{
+		Name:        "Frost Risk",
+		ID:          "frost_risk",
+		DeviceClass: strPtr("cold"),
+		Component:   ComponentBinarySensor,
+	},
+	{
+		Name:      "High Wind",
+		ID:        "high_wind",
+		Icon:      "mdi:weather-windy",
+		Component: ComponentBinarySensor,
+	},
+	{
+		Name:        "Daylight",
+		ID:          "daylight",
+		DeviceClass: strPtr("light"),
+		Component:   ComponentBinarySensor,
+	},
+}
+
+// MaxChannels is the number of extra sensor channels supported per family.
+const MaxChannels = 8
+
+// channelFamily describes a sensor type repeated across numbered channels.
+type channelFamily struct {
+	SensorDefinition        // Template; ID and QueryParam are used as prefixes
+	ParamSuffix      string // Suffix after the channel number (e.g. "f" in "temp2f")
+	BareFirstChannel bool   // Channel 1 omits the number (e.g. "soiltempf")
+}
+
+// channelFamilies contains the extra sensor channels supported by the bridge.
+var channelFamilies = []channelFamily{
+	{
+		SensorDefinition: SensorDefinition{
+			Name:         "Temperature",
+			ID:           "temperature",
+			QueryParam:   "temp",
+			DeviceClass:  strPtr("temperature"),
+			MetricUnit:   "°C",
+			ImperialUnit: "°F",
+			StateClass:   "measurement",
+			Precision:    1,
+			ChannelGroup: "temp",
+		},
+		ParamSuffix: "f",
+	},
+	{
+		SensorDefinition: SensorDefinition{
+			Name:         "Humidity",
+			ID:           "humidity",
+			QueryParam:   "humidity",
+			DeviceClass:  strPtr("humidity"),
+			MetricUnit:   "%",
+			ImperialUnit: "%",
+			StateClass:   "measurement",
+			Precision:    0,
+			ChannelGroup: "temp",
+		},
+	},
+	{
+		SensorDefinition: SensorDefinition{
+			Name:         "Soil Temperature",
+			ID:           "soil_temperature",
+			QueryParam:   "soiltemp",
+			DeviceClass:  strPtr("temperature"),
+			MetricUnit:   "°C",
+			ImperialUnit: "°F",
+			StateClass:   "measurement",
+			Precision:    1,
+			ChannelGroup: "soil",
+		},
+		ParamSuffix:      "f",
+		BareFirstChannel: true,
+	},
+	{
+		SensorDefinition: SensorDefinition{
+			Name:         "Soil Moisture",
+			ID:           "soil_moisture",
+			QueryParam:   "soilmoisture",
+			DeviceClass:  strPtr("moisture"),
+			MetricUnit:   "%",
+			ImperialUnit: "%",
+			StateClass:   "measurement",
+			Precision:    0,
+			ChannelGroup: "soil",
+		},
+		BareFirstChannel: true,
+	},
+	{
+		SensorDefinition: SensorDefinition{
+			Name:         "Leaf Wetness",
+			ID:           "leaf_wetness",
+			QueryParam:   "leafwetness",
+			DeviceClass:  nil,
+			MetricUnit:   "%",
+			ImperialUnit: "%",
+			Icon:         "mdi:leaf",
+			StateClass:   "measurement",
+			Precision:    0,
+			ChannelGroup: "leaf",
+		},
+		BareFirstChannel: true,
+	},
+	{
+		SensorDefinition: SensorDefinition{
+			Name:         "Sensor Battery",
+			ID:           "battery",
+			QueryParam:   "batt",
+			DeviceClass:  nil,
+			MetricUnit:   "",
+			ImperialUnit: "",
+			Icon:         "mdi:battery",
+			StateClass:   "measurement",
+			Precision:    0,
+			ChannelGroup: "temp",
+		},
+	},
+	{
+		SensorDefinition: SensorDefinition{
+			Name:         "Soil Sensor Battery",
+			ID:           "soil_battery",
+			QueryParam:   "battsm",
+			DeviceClass:  nil,
+			MetricUnit:   "",
+			ImperialUnit: "",
+			Icon:         "mdi:battery",
+			StateClass:   "measurement",
+			Precision:    0,
+			ChannelGroup: "soil",
+		},
+	},
+}
+
+// Channel sensors are generated for every family and channel. Home Assistant
+// entities only appear for channels that are actually reported by the station.
+func init() {
+	for _, family := range channelFamilies {
+		for n := 1; n <= MaxChannels; n++ {
+			sensor := family.SensorDefinition
+			sensor.ID = fmt.Sprintf("%s_ch%d", family.ID, n)
+			sensor.QueryParam = family.param(n)
+			sensor.Channel = n
+			SensorDefinitions = append(SensorDefinitions, sensor)
+		}
+	}
+}
+
+// param returns the Weather Underground parameter for a channel.
+func (f *channelFamily) param(n int) string {
+	if n == 1 && f.BareFirstChannel {
+		return f.QueryParam + f.ParamSuffix
+	}
+	return fmt.Sprintf("%s%d%s", f.QueryParam, n, f.ParamSuffix)
+}
+
+// channelParam returns the Weather Underground parameter for a channel of the
+// family with the given ID prefix (e.g. "soil_moisture").
+func channelParam(familyID string, n int) string {
+	for i := range channelFamilies {
+		if channelFamilies[i].ID == familyID {
+			return channelFamilies[i].param(n)
+		}
+	}
+	return ""
 }
 
 // GetSensorByQueryParam returns the sensor definition for a query parameter.
@@ -159,6 +666,35 @@ func GetSensorByQueryParam(param string) *SensorDefinition {
 	return nil
 }
 
+// GetSensorByID returns the definition of any published sensor, including
+// text, binary and diagnostic sensors, by its ID.
+func GetSensorByID(id string) *SensorDefinition {
+	for _, definitions := range [][]SensorDefinition{
+		SensorDefinitions, TextSensorDefinitions, BinarySensorDefinitions, DiagnosticSensorDefinitions,
+	} {
+		for i := range definitions {
+			if definitions[i].ID == id {
+				return &definitions[i]
+			}
+		}
+	}
+	return nil
+}
+
+// stationData reports whether the sensor carries weather data from the
+// station, as opposed to diagnostics about the station or the bridge.
+func (s *SensorDefinition) stationData() bool {
+	return s.EntityCategory == "" && !s.Bridge
+}
+
+// component returns the Home Assistant component of the sensor.
+func (s *SensorDefinition) component() string {
+	if s.Component == "" {
+		return ComponentSensor
+	}
+	return s.Component
+}
+
 // GetUnit returns the appropriate unit based on metric/imperial setting.
 func (s *SensorDefinition) GetUnit(isMetric bool) string {
 	if isMetric {
@@ -166,3 +702,15 @@ func (s *SensorDefinition) GetUnit(isMetric bool) string {
 	}
 	return s.ImperialUnit
 }
+
+// DisplayName returns the human-readable sensor name. Channel sensors use the
+// user-assigned channel name if one is configured (e.g. "temp2=Greenhouse").
+func (s *SensorDefinition) DisplayName(channelNames map[string]string) string {
+	if s.Channel == 0 {
+		return s.Name
+	}
+	if name := channelNames[fmt.Sprintf("%s%d", s.ChannelGroup, s.Channel)]; name != "" {
+		return fmt.Sprintf("%s %s", name, s.Name)
+	}
+	return fmt.Sprintf("%s Channel %d", s.Name, s.Channel)
+}
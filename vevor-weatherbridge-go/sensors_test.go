@@ -0,0 +1,111 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+// Copyright (C) 2025 Lenucksi
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import "testing"
+
+// knownQueryParam reports whether a sensor, including a diagnostic one, reads
+// the given Weather Underground parameter.
+func knownQueryParam(param string) bool {
+	if GetSensorByQueryParam(param) != nil {
+		return true
+	}
+	for _, sensor := range DiagnosticSensorDefinitions {
+		if sensor.QueryParam == param {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSensorDefinitionsUnique(t *testing.T) {
+	ids := make(map[string]bool)
+	params := make(map[string]bool)
+	for _, sensor := range SensorDefinitions {
+		if ids[sensor.ID] {
+			t.Errorf("duplicate sensor ID %q", sensor.ID)
+		}
+		if params[sensor.QueryParam] {
+			t.Errorf("duplicate query parameter %q", sensor.QueryParam)
+		}
+		ids[sensor.ID] = true
+		params[sensor.QueryParam] = true
+	}
+}
+
+func TestChannelSensorDefinitions(t *testing.T) {
+	tests := []struct {
+		param   string
+		id      string
+		channel int
+	}{
+		{"temp1f", "temperature_ch1", 1},
+		{"temp8f", "temperature_ch8", 8},
+		{"humidity2", "humidity_ch2", 2},
+		{"soiltempf", "soil_temperature_ch1", 1},
+		{"soiltemp3f", "soil_temperature_ch3", 3},
+		{"soilmoisture", "soil_moisture_ch1", 1},
+		{"soilmoisture4", "soil_moisture_ch4", 4},
+		{"leafwetness", "leaf_wetness_ch1", 1},
+		{"leafwetness2", "leaf_wetness_ch2", 2},
+		{"batt1", "battery_ch1", 1},
+		{"battsm2", "soil_battery_ch2", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.param, func(t *testing.T) {
+			sensor := GetSensorByQueryParam(tt.param)
+			if sensor == nil {
+				t.Fatalf("no sensor definition for %q", tt.param)
+			}
+			if sensor.ID != tt.id {
+				t.Errorf("ID = %q, want %q", sensor.ID, tt.id)
+			}
+			if sensor.Channel != tt.channel {
+				t.Errorf("Channel = %d, want %d", sensor.Channel, tt.channel)
+			}
+		})
+	}
+}
+
+func TestSensorDisplayName(t *testing.T) {
+	names := map[string]string{"temp2": "Greenhouse", "soil1": "Raised Bed"}
+
+	tests := []struct {
+		param    string
+		expected string
+	}{
+		{"tempf", "Temperature"},
+		{"temp2f", "Greenhouse Temperature"},
+		{"humidity2", "Greenhouse Humidity"},
+		{"temp3f", "Temperature Channel 3"},
+		{"soilmoisture", "Raised Bed Soil Moisture"},
+		{"leafwetness", "Leaf Wetness Channel 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.param, func(t *testing.T) {
+			sensor := GetSensorByQueryParam(tt.param)
+			if sensor == nil {
+				t.Fatalf("no sensor definition for %q", tt.param)
+			}
+			if got := sensor.DisplayName(names); got != tt.expected {
+				t.Errorf("DisplayName() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+// Copyright (C) 2025 Lenucksi
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// Kinds of messages published to a broker. Each kind has its own topic
+// template, QoS and retain flag.
+const (
+	KindConfig       = "config"
+	KindState        = "state"
+	KindAttributes   = "attributes"
+	KindAvailability = "availability"
+	KindStation      = "station"
+)
+
+// MessageKinds lists all message kinds.
+var MessageKinds = []string{KindConfig, KindState, KindAttributes, KindAvailability, KindStation}
+
+// defaultTopics holds the topic templates of each topic style by message kind.
+var defaultTopics = map[string]map[string]string{
+	TopicStyleDiscovery: {
+		KindConfig:       "{{.Prefix}}/{{.Component}}/{{.DeviceID}}_{{.SensorID}}/config",
+		KindState:        "{{.Prefix}}/{{.Component}}/{{.DeviceID}}_{{.SensorID}}/state",
+		KindAttributes:   "{{.Prefix}}/{{.Component}}/{{.DeviceID}}_{{.SensorID}}/attributes",
+		KindAvailability: "{{.Prefix}}/{{.Component}}/{{.DeviceID}}/availability",
+		KindStation:      "{{.Prefix}}/{{.Component}}/{{.DeviceID}}/station",
+	},
+	TopicStyleFlat: {
+		KindConfig:       "{{.Prefix}}/{{.DeviceID}}/{{.SensorID}}/config",
+		KindState:        "{{.Prefix}}/{{.DeviceID}}/{{.SensorID}}",
+		KindAttributes:   "{{.Prefix}}/{{.DeviceID}}/{{.SensorID}}/attributes",
+		KindAvailability: "{{.Prefix}}/{{.DeviceID}}/availability",
+		KindStation:      "{{.Prefix}}/{{.DeviceID}}/station",
+	},
+}
+
+// defaultJSONStateTopics holds the state document topic template of each
+// topic style in JSON state mode.
+var defaultJSONStateTopics = map[string]string{
+	TopicStyleDiscovery: "{{.Prefix}}/{{.Component}}/{{.DeviceID}}/state",
+	TopicStyleFlat:      "{{.Prefix}}/{{.DeviceID}}/state",
+}
+
+// TopicData holds the values available to topic templates.
+type TopicData struct {
+	Prefix    string
+	DeviceID  string
+	SensorID  string
+	Component string
+}
+
+// MessageOverride holds the user-supplied topic template, QoS and retain flag
+// of a message kind. Unset values use the broker's defaults.
+type MessageOverride struct {
+	Topic  string
+	QoS    *int
+	Retain *bool
+}
+
+// messageSettings holds the resolved publishing settings of a message kind.
+type messageSettings struct {
+	topic  *template.Template
+	qos    byte
+	retain bool
+}
+
+// messageOverrides reads the per-kind overrides using lookup, which returns
+// the value of keys such as "state_topic", "state_qos" and "state_retain".
+// Invalid values are ignored.
+func messageOverrides(lookup func(key string) string) map[string]MessageOverride {
+	var overrides map[string]MessageOverride
+	for _, kind := range MessageKinds {
+		var o MessageOverride
+		set := false
+
+		if topic := lookup(kind + "_topic"); topic != "" {
+			o.Topic = topic
+			set = true
+		}
+		if value := lookup(kind + "_qos"); value != "" {
+			if qos, err := strconv.Atoi(value); err == nil && qos >= 0 && qos <= 2 {
+				o.QoS = &qos
+				set = true
+			} else {
+				slog.Warn("Ignoring invalid MQTT QoS", "kind", kind, "qos", value)
+			}
+		}
+		if value := lookup(kind + "_retain"); value != "" {
+			switch strings.ToLower(value) {
+			case "true", "1", "yes":
+				retain := true
+				o.Retain = &retain
+				set = true
+			case "false", "0", "no":
+				retain := false
+				o.Retain = &retain
+				set = true
+			default:
+				slog.Warn("Ignoring invalid MQTT retain flag", "kind", kind, "retain", value)
+			}
+		}
+
+		if set {
+			if overrides == nil {
+				overrides = make(map[string]MessageOverride)
+			}
+			overrides[kind] = o
+		}
+	}
+	return overrides
+}
+
+// newMessageSettings resolves the publishing settings of every message kind
+// for a broker. Messages are retained with the broker's QoS unless
+// overridden. In JSON state mode, the state topic has no sensor ID. Topic
+// templates that fail to parse or render fall back to the default of the
+// broker's topic style.
+func newMessageSettings(broker *BrokerConfig) map[string]messageSettings {
+	style := broker.TopicStyle
+	if _, ok := defaultTopics[style]; !ok {
+		style = TopicStyleDiscovery
+	}
+	defaults := defaultTopics[style]
+
+	settings := make(map[string]messageSettings, len(MessageKinds))
+	for _, kind := range MessageKinds {
+		topic := defaults[kind]
+		perSensor := kind == KindConfig || kind == KindAttributes || kind == KindState
+		if kind == KindState && broker.StateMode == StateModeJSON {
+			topic = defaultJSONStateTopics[style]
+			perSensor = false
+		}
+
+		s := messageSettings{
+			topic:  template.Must(parseTopicTemplate(kind, topic, perSensor)),
+			qos:    byte(broker.QoS),
+			retain: true,
+		}
+
+		o := broker.Messages[kind]
+		if o.Topic != "" {
+			if tmpl, err := parseTopicTemplate(kind, o.Topic, perSensor); err == nil {
+				s.topic = tmpl
+			} else {
+				slog.Warn("Ignoring invalid MQTT topic template", "broker", broker.Name, "kind", kind, "error", err)
+			}
+		}
+		if o.QoS != nil {
+			s.qos = byte(*o.QoS)
+		}
+		if o.Retain != nil {
+			s.retain = *o.Retain
+		}
+		settings[kind] = s
+	}
+	return settings
+}
+
+// parseTopicTemplate parses a topic template and checks that it renders. The
+// topics of per-sensor kinds must differ between sensors, or every sensor
+// would overwrite the others.
+func parseTopicTemplate(kind, text string, perSensor bool) (*template.Template, error) {
+	tmpl, err := template.New(kind).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	sample := TopicData{Prefix: "homeassistant", DeviceID: "device", SensorID: "sensor", Component: "sensor"}
+	topic, err := renderSampleTopic(tmpl, sample)
+	if err != nil {
+		return nil, err
+	}
+	if perSensor {
+		sample.SensorID = "other_sensor"
+		other, err := renderSampleTopic(tmpl, sample)
+		if err != nil {
+			return nil, err
+		}
+		if other == topic {
+			return nil, fmt.Errorf("topic %q does not contain the sensor ID", topic)
+		}
+	}
+	return tmpl, nil
+}
+
+// renderSampleTopic renders a topic template with sample data and checks
+// that the topic is usable for publishing.
+func renderSampleTopic(tmpl *template.Template, sample TopicData) (string, error) {
+	var topic strings.Builder
+	if err := tmpl.Execute(&topic, sample); err != nil {
+		return "", err
+	}
+	if topic.Len() == 0 || strings.ContainsAny(topic.String(), "+#") {
+		return "", fmt.Errorf("invalid topic %q", topic.String())
+	}
+	return topic.String(), nil
+}
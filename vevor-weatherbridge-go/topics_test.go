@@ -0,0 +1,125 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+// Copyright (C) 2025 Lenucksi
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import "testing"
+
+func TestMessageOverrides(t *testing.T) {
+	values := map[string]string{
+		"state_topic":       "weather/{{.DeviceID}}/{{.SensorID}}",
+		"state_qos":         "0",
+		"state_retain":      "false",
+		"attributes_retain": "yes",
+		"config_qos":        "3",
+		"availability_qos":  "x",
+	}
+	overrides := messageOverrides(func(key string) string { return values[key] })
+
+	state, ok := overrides[KindState]
+	if !ok || state.Topic != values["state_topic"] || state.QoS == nil || *state.QoS != 0 ||
+		state.Retain == nil || *state.Retain {
+		t.Errorf("state override = %+v", state)
+	}
+	if attrs := overrides[KindAttributes]; attrs.Retain == nil || !*attrs.Retain || attrs.QoS != nil {
+		t.Errorf("attributes override = %+v", attrs)
+	}
+	// Invalid QoS values are ignored
+	for _, kind := range []string{KindConfig, KindAvailability} {
+		if o, ok := overrides[kind]; ok {
+			t.Errorf("%s override = %+v, want none", kind, o)
+		}
+	}
+
+	if got := messageOverrides(func(string) string { return "" }); got != nil {
+		t.Errorf("messageOverrides() without values = %v, want nil", got)
+	}
+}
+
+func TestCustomTopicsQoSAndRetain(t *testing.T) {
+	qos := 2
+	retain := false
+	broker := BrokerConfig{
+		Name:       PrimaryBrokerName,
+		Prefix:     "site1",
+		TopicStyle: TopicStyleDiscovery,
+		QoS:        1,
+		Messages: map[string]MessageOverride{
+			KindState:        {Topic: "{{.Prefix}}/weather/{{.DeviceID}}/{{.SensorID}}", QoS: &qos, Retain: &retain},
+			KindAttributes:   {Topic: "{{.Prefix}}/weather/{{.Unknown}}"},
+			KindAvailability: {Topic: "{{.Prefix}}/#"},
+			KindConfig:       {Topic: "{{.Prefix"},
+		},
+	}
+	fake := &fakeClient{}
+	m := newMQTTClient(testConfig(), broker)
+	m.client = fake
+	temperature := GetSensorByID("temperature")
+
+	topics := map[string]string{
+		m.StateTopic(temperature):      "site1/weather/weather_station/temperature",
+		m.AttributesTopic(temperature): "site1/sensor/weather_station_temperature/attributes",
+		m.AvailabilityTopic():          "site1/sensor/weather_station/availability",
+		m.ConfigTopic(temperature):     "site1/sensor/weather_station_temperature/config",
+	}
+	for got, want := range topics {
+		if got != want {
+			t.Errorf("topic = %q, want %q", got, want)
+		}
+	}
+
+	if err := m.publishState(temperature, "21.5", ""); err != nil {
+		t.Fatalf("publishState() error: %v", err)
+	}
+	if err := m.PublishSensorAttributes(temperature, map[string]interface{}{}); err != nil {
+		t.Fatalf("PublishSensorAttributes() error: %v", err)
+	}
+
+	state := fake.published("site1/weather/weather_station/temperature")
+	if len(state) != 1 || state[0].QoS != 2 || state[0].Retained {
+		t.Errorf("state messages = %+v, want QoS 2 and not retained", state)
+	}
+	attrs := fake.published("site1/sensor/weather_station_temperature/attributes")
+	if len(attrs) != 1 || attrs[0].QoS != 1 || !attrs[0].Retained {
+		t.Errorf("attributes messages = %+v, want QoS 1 and retained", attrs)
+	}
+}
+
+func TestParseTopicTemplate(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		perSensor bool
+		valid     bool
+	}{
+		{"per-sensor topic", "{{.Prefix}}/{{.DeviceID}}/{{.SensorID}}", true, true},
+		{"per-sensor topic without sensor ID", "{{.Prefix}}/{{.DeviceID}}/state", true, false},
+		{"shared topic", "{{.Prefix}}/{{.DeviceID}}/state", false, true},
+		{"wildcard", "{{.Prefix}}/+/{{.SensorID}}", true, false},
+		{"empty", "{{if false}}x{{end}}", false, false},
+		{"parse error", "{{.Prefix", false, false},
+		{"unknown field", "{{.Unknown}}", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseTopicTemplate(KindState, tt.text, tt.perSensor)
+			if (err == nil) != tt.valid {
+				t.Errorf("parseTopicTemplate(%q) error = %v, want valid %v", tt.text, err, tt.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+// Copyright (C) 2025 Lenucksi
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// StationWatchdog tracks whether the weather station is sending updates. The
+// station counts as offline until the first update and again when no update
+// has arrived within the timeout.
+type StationWatchdog struct {
+	timeout   time.Duration
+	notify    func(online bool)
+	timer     *time.Timer
+	lastSeen  time.Time
+	online    bool
+	notified  bool // last status passed to notify
+	notifying bool // a goroutine is calling notify
+	stopped   bool
+	mu        sync.Mutex
+}
+
+// NewStationWatchdog creates a watchdog calling notify whenever the station
+// goes online or offline. notify runs in its own goroutine, so it may block
+// (e.g. on an MQTT publish) without delaying the station's updates. Status
+// changes are passed in order; if several happen while notify is running,
+// only the latest one is passed.
+func NewStationWatchdog(timeout time.Duration, notify func(online bool)) *StationWatchdog {
+	return &StationWatchdog{timeout: timeout, notify: notify}
+}
+
+// Seen records an update from the station.
+func (w *StationWatchdog) Seen() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.lastSeen = time.Now()
+	if w.timer == nil {
+		w.timer = time.AfterFunc(w.timeout, w.expire)
+	} else {
+		w.timer.Reset(w.timeout)
+	}
+
+	if !w.online {
+		slog.Info("Weather station online")
+		w.setOnline(true)
+	}
+}
+
+// expire marks the station offline after the timeout. The timer may fire
+// while Seen is resetting it, so the time of the last update is checked
+// again.
+func (w *StationWatchdog) expire() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.online || time.Since(w.lastSeen) < w.timeout {
+		return
+	}
+	slog.Warn("No update from weather station, marking it offline", "timeout", w.timeout)
+	w.setOnline(false)
+}
+
+// setOnline changes the station status and starts notifying, unless a
+// notification is already running. The caller must hold mu.
+func (w *StationWatchdog) setOnline(online bool) {
+	w.online = online
+	if !w.notifying && !w.stopped {
+		w.notifying = true
+		go w.deliver()
+	}
+}
+
+// deliver calls notify until the last status passed matches the current one.
+func (w *StationWatchdog) deliver() {
+	for {
+		w.mu.Lock()
+		online := w.online
+		if online == w.notified || w.stopped {
+			w.notifying = false
+			w.mu.Unlock()
+			return
+		}
+		w.notified = online
+		w.mu.Unlock()
+
+		w.notify(online)
+	}
+}
+
+// Stop stops the watchdog timer and any further notifications, e.g. before
+// the brokers are closed.
+func (w *StationWatchdog) Stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stopped = true
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+}
@@ -0,0 +1,128 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+// Copyright (C) 2025 Lenucksi
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStationWatchdog(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		changes []bool
+	)
+	w := NewStationWatchdog(50*time.Millisecond, func(online bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		changes = append(changes, online)
+	})
+	defer w.Stop()
+
+	recorded := func() []bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]bool(nil), changes...)
+	}
+	// Notifications are asynchronous, wait for the expected number
+	waitFor := func(n int) []bool {
+		deadline := time.Now().Add(2 * time.Second)
+		for len(recorded()) < n && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		return recorded()
+	}
+
+	// Updates within the timeout keep the station online
+	for range 4 {
+		w.Seen()
+		time.Sleep(20 * time.Millisecond)
+	}
+	if got := recorded(); len(got) != 1 || !got[0] {
+		t.Fatalf("changes = %v, want [true]", got)
+	}
+
+	// No update within the timeout
+	if got := waitFor(2); len(got) != 2 || got[1] {
+		t.Fatalf("changes = %v, want [true false]", got)
+	}
+
+	// The next update brings the station back
+	w.Seen()
+	if got := waitFor(3); len(got) != 3 || !got[2] {
+		t.Errorf("changes = %v, want [true false true]", got)
+	}
+}
+
+func TestStationWatchdogLateExpiry(t *testing.T) {
+	notified := make(chan bool, 2)
+	w := NewStationWatchdog(time.Hour, func(online bool) { notified <- online })
+	defer w.Stop()
+
+	w.Seen()
+	if online := <-notified; !online {
+		t.Fatal("station not reported online")
+	}
+
+	// A timer that fired just before an update must not mark the station offline
+	w.expire()
+	select {
+	case online := <-notified:
+		t.Errorf("unexpected change to online=%v", online)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestStationWatchdogDoesNotBlock(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	w := NewStationWatchdog(time.Hour, func(bool) { <-release })
+	defer w.Stop()
+
+	done := make(chan struct{})
+	go func() {
+		w.Seen()
+		w.Seen()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Seen() blocked on a slow notify")
+	}
+}
+
+func TestStationWatchdogStop(t *testing.T) {
+	notified := make(chan bool, 2)
+	w := NewStationWatchdog(20*time.Millisecond, func(online bool) { notified <- online })
+
+	w.Seen()
+	if online := <-notified; !online {
+		t.Fatal("station not reported online")
+	}
+
+	// No status is published after stopping, e.g. to brokers being closed
+	w.Stop()
+	time.Sleep(30 * time.Millisecond)
+	w.expire()
+	select {
+	case online := <-notified:
+		t.Errorf("unexpected change to online=%v after Stop", online)
+	case <-time.After(60 * time.Millisecond):
+	}
+}
@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+// Copyright (C) 2025 Lenucksi
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math"
+	"net/url"
+	"time"
+)
+
+// windWindow describes an aggregation window for wind speed and direction.
+// Empty parameter names are not published for that window.
+type windWindow struct {
+	Duration time.Duration
+	SpeedAvg string // Parameter for the average wind speed
+	DirAvg   string // Parameter for the vector-averaged wind direction
+	GustMax  string // Parameter for the maximum gust
+}
+
+// windWindows contains the wind aggregation windows, using Weather Underground
+// parameter names where the protocol defines them.
+var windWindows = []windWindow{
+	{Duration: 2 * time.Minute, SpeedAvg: "windspdmph_avg2m", DirAvg: "winddir_avg2m"},
+	{Duration: 10 * time.Minute, SpeedAvg: "windspdmph_avg10m", DirAvg: "winddir_avg10m", GustMax: "windgustmph_10m"},
+	{Duration: time.Hour, GustMax: "windgustmph_1h"},
+}
+
+// VectorAverageDirection returns the average wind direction in degrees of
+// the given wind vector components. Returns NaN for a calm (zero) vector.
+func VectorAverageDirection(u, v float64) float64 {
+	if math.Hypot(u, v) < 1e-9 {
+		return math.NaN()
+	}
+	dir := math.Atan2(u, v) * 180 / math.Pi
+	if dir < 0 {
+		dir += 360
+	}
+	return dir
+}
+
+// windAggregates records the current wind readings and adds rolling averages
+// and maximum gusts to params. Values sent by the station win.
+func (h *WeatherHandler) windAggregates(params url.Values, now time.Time) {
+	speed, hasSpeed := paramFloat(params, "windspeedmph")
+	dir, hasDir := paramFloat(params, "winddir")
+	gust, hasGust := paramFloat(params, "windgustmph")
+
+	if hasSpeed {
+		h.history.Add("wind_speed", now, speed)
+		if hasDir {
+			// Direction is averaged as a speed-weighted vector so that
+			// 359° and 1° average to 0° rather than 180°
+			rad := dir * math.Pi / 180
+			h.history.Add("wind_vector_u", now, speed*math.Sin(rad))
+			h.history.Add("wind_vector_v", now, speed*math.Cos(rad))
+		}
+	}
+	if hasGust {
+		h.history.Add("wind_gust_speed", now, gust)
+		if !params.Has("maxdailygust") {
+			setDerived(params, "maxdailygust", h.dailyGust.Add(now.In(h.cfg.Timezone), gust))
+		}
+	}
+
+	for _, w := range windWindows {
+		since := now.Add(-w.Duration)
+
+		if w.SpeedAvg != "" && !params.Has(w.SpeedAvg) {
+			if avg, ok := average(h.history.Since("wind_speed", since)); ok {
+				setDerived(params, w.SpeedAvg, avg)
+			}
+		}
+
+		if w.DirAvg != "" && !params.Has(w.DirAvg) {
+			u, okU := average(h.history.Since("wind_vector_u", since))
+			v, okV := average(h.history.Since("wind_vector_v", since))
+			if okU && okV {
+				// Directions just below north round up to 360, which is 0
+				setDerived(params, w.DirAvg, math.Mod(math.Round(VectorAverageDirection(u, v)), 360))
+			}
+		}
+
+		if w.GustMax != "" && !params.Has(w.GustMax) {
+			if peak, ok := maximum(h.history.Since("wind_gust_speed", since)); ok {
+				setDerived(params, w.GustMax, peak)
+			}
+		}
+	}
+}
+
+// average returns the mean value of samples.
+func average(samples []Sample) (float64, bool) {
+	if len(samples) == 0 {
+		return 0, false
+	}
+	sum := 0.0
+	for _, s := range samples {
+		sum += s.Value
+	}
+	return sum / float64(len(samples)), true
+}
+
+// maximum returns the largest value of samples.
+func maximum(samples []Sample) (float64, bool) {
+	if len(samples) == 0 {
+		return 0, false
+	}
+	peak := samples[0].Value
+	for _, s := range samples[1:] {
+		peak = math.Max(peak, s.Value)
+	}
+	return peak, true
+}
@@ -0,0 +1,133 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+// Copyright (C) 2025 Lenucksi
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestVectorAverageDirection(t *testing.T) {
+	vector := func(dirs ...float64) (float64, float64) {
+		var u, v float64
+		for _, d := range dirs {
+			u += math.Sin(d * math.Pi / 180)
+			v += math.Cos(d * math.Pi / 180)
+		}
+		return u / float64(len(dirs)), v / float64(len(dirs))
+	}
+
+	tests := []struct {
+		name     string
+		dirs     []float64
+		expected float64
+	}{
+		{"wrap around north", []float64{359, 1}, 0},
+		{"east", []float64{80, 100}, 90},
+		{"south", []float64{170, 190}, 180},
+		{"northwest wrap", []float64{350, 290}, 320},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, v := vector(tt.dirs...)
+			result := VectorAverageDirection(u, v)
+			diff := math.Abs(math.Mod(result-tt.expected+540, 360) - 180)
+			if diff > 0.01 {
+				t.Errorf("VectorAverageDirection(%v) = %v, want %v", tt.dirs, result, tt.expected)
+			}
+		})
+	}
+
+	if result := VectorAverageDirection(0, 0); !math.IsNaN(result) {
+		t.Errorf("VectorAverageDirection(0, 0) = %v, want NaN", result)
+	}
+}
+
+func TestWindAggregates(t *testing.T) {
+	h := &WeatherHandler{
+		cfg:     &Config{Units: "metric", Timezone: time.UTC},
+		history: NewHistory(time.Hour),
+	}
+	start := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+
+	readings := []struct {
+		offset time.Duration
+		speed  string
+		dir    string
+		gust   string
+	}{
+		{0, "4", "350", "12"},
+		{5 * time.Minute, "6", "355", "9"},
+		{9 * time.Minute, "10", "10", "14"},
+		{10 * time.Minute, "8", "5", "11"},
+	}
+
+	var params url.Values
+	for _, r := range readings {
+		params = url.Values{"windspeedmph": {r.speed}, "winddir": {r.dir}, "windgustmph": {r.gust}}
+		h.windAggregates(params, start.Add(r.offset))
+	}
+
+	expected := map[string]float64{
+		"windspdmph_avg2m":  9,  // 10 and 8
+		"windspdmph_avg10m": 7,  // all four samples
+		"windgustmph_10m":   14, // max of all four
+		"windgustmph_1h":    14,
+		"maxdailygust":      14,
+		"winddir_avg2m":     8,   // speed-weighted 10° and 5°
+		"winddir_avg10m":    2.5, // wraps around north, rounded to whole degrees
+	}
+	for param, want := range expected {
+		got, ok := paramFloat(params, param)
+		if !ok {
+			t.Errorf("%s not derived", param)
+			continue
+		}
+		if math.Abs(got-want) > 0.5 {
+			t.Errorf("%s = %v, want %v", param, got, want)
+		}
+	}
+}
+
+func TestWindAggregatesDirectionBelowNorth(t *testing.T) {
+	h := &WeatherHandler{
+		cfg:     &Config{Units: "metric", Timezone: time.UTC},
+		history: NewHistory(time.Hour),
+	}
+
+	params := url.Values{"windspeedmph": {"5"}, "winddir": {"359.7"}}
+	h.windAggregates(params, time.Now())
+	if got, ok := paramFloat(params, "winddir_avg2m"); !ok || got != 0 {
+		t.Errorf("winddir_avg2m = %v, want 0", got)
+	}
+}
+
+func TestWindAggregatesStationValuesWin(t *testing.T) {
+	h := &WeatherHandler{
+		cfg:     &Config{Units: "metric", Timezone: time.UTC},
+		history: NewHistory(time.Hour),
+	}
+
+	params := url.Values{"windspeedmph": {"5"}, "windgustmph": {"9"}, "maxdailygust": {"20.0"}}
+	h.windAggregates(params, time.Now())
+	if got := params.Get("maxdailygust"); got != "20.0" {
+		t.Errorf("maxdailygust = %q, want station value 20.0", got)
+	}
+}
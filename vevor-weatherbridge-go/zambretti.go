@@ -0,0 +1,135 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+// Copyright (C) 2025 Lenucksi
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math"
+	"net/url"
+	"time"
+)
+
+// Zambretti forecaster pressure range in hPa.
+const (
+	zambrettiTop    = 1050.0
+	zambrettiBottom = 950.0
+	zambrettiRange  = zambrettiTop - zambrettiBottom
+	// zambrettiSteady is the 3-hour change in hPa below which pressure is steady.
+	zambrettiSteady = 1.6
+)
+
+// zambrettiForecasts contains the forecast texts for letters A to Z.
+var zambrettiForecasts = []string{
+	"Settled fine",
+	"Fine weather",
+	"Becoming fine",
+	"Fine, becoming less settled",
+	"Fine, possible showers",
+	"Fairly fine, improving",
+	"Fairly fine, possible showers early",
+	"Fairly fine, showery later",
+	"Showery early, improving",
+	"Changeable, mending",
+	"Fairly fine, showers likely",
+	"Rather unsettled clearing later",
+	"Unsettled, probably improving",
+	"Showery, bright intervals",
+	"Showery, becoming less settled",
+	"Changeable, some rain",
+	"Unsettled, short fine intervals",
+	"Unsettled, rain later",
+	"Unsettled, some rain",
+	"Mostly very unsettled",
+	"Occasional rain, worsening",
+	"Rain at times, very unsettled",
+	"Rain at frequent intervals",
+	"Rain, very unsettled",
+	"Stormy, may improve",
+	"Stormy, much rain",
+}
+
+// Forecast indices for each of the 22 pressure bands, highest pressure last.
+var (
+	zambrettiRisingOptions  = []int{25, 25, 25, 24, 24, 19, 16, 12, 11, 9, 8, 6, 5, 2, 1, 1, 0, 0, 0, 0, 0, 0}
+	zambrettiSteadyOptions  = []int{25, 25, 25, 25, 25, 25, 23, 23, 22, 18, 15, 13, 10, 4, 1, 1, 0, 0, 0, 0, 0, 0}
+	zambrettiFallingOptions = []int{25, 25, 25, 25, 25, 25, 25, 25, 23, 23, 21, 20, 17, 14, 7, 3, 1, 1, 1, 0, 0, 0}
+)
+
+// zambrettiWind contains the pressure adjustment in percent of the range for
+// each cardinal wind direction (northern hemisphere).
+var zambrettiWind = map[string]float64{
+	"N": 6, "NNE": 5, "NE": 5, "ENE": 2,
+	"E": -0.5, "ESE": -2, "SE": -5, "SSE": -8.5,
+	"S": -12, "SSW": -10, "SW": -6, "WSW": -4.5,
+	"W": -3, "WNW": -0.5, "NW": 1.5, "NNW": 3,
+}
+
+// ZambrettiForecast returns the Zambretti letter and forecast text for a
+// sea-level pressure in hPa and its 3-hour change. The wind direction in
+// degrees is only used if hasWind is set.
+func ZambrettiForecast(pressureHPa, deltaHPa, windDir float64, hasWind bool, month time.Month, southern bool) (string, string) {
+	summer := month >= time.April && month <= time.September
+	if southern {
+		summer = !summer
+	}
+
+	if hasWind {
+		// Southern hemisphere winds have the opposite effect
+		if southern {
+			windDir += 180
+		}
+		pressureHPa += zambrettiWind[DegreesToCardinal(windDir)] / 100 * zambrettiRange
+	}
+
+	options := zambrettiSteadyOptions
+	switch {
+	case deltaHPa >= zambrettiSteady:
+		options = zambrettiRisingOptions
+		if summer {
+			pressureHPa += 7.0 / 100 * zambrettiRange
+		}
+	case deltaHPa <= -zambrettiSteady:
+		options = zambrettiFallingOptions
+		if summer {
+			pressureHPa -= 7.0 / 100 * zambrettiRange
+		}
+	}
+
+	band := int(math.Floor((pressureHPa - zambrettiBottom) / (zambrettiRange / 22)))
+	band = min(max(band, 0), 21)
+
+	index := options[band]
+	return string(rune('A' + index)), zambrettiForecasts[index]
+}
+
+// zambrettiForecast returns the Zambretti letter and forecast text for the
+// current update, given the 3-hour pressure change in hPa.
+func (h *WeatherHandler) zambrettiForecast(params url.Values, deltaHPa float64, now time.Time) (string, string, bool) {
+	pressure, ok := paramFloat(params, "baromin")
+	if !ok {
+		return "", "", false
+	}
+
+	// Wind direction is meaningless in calm conditions
+	windDir, hasWind := paramFloat(params, "winddir")
+	if speed, ok := paramFloat(params, "windspeedmph"); ok && speed == 0 {
+		hasWind = false
+	}
+
+	letter, text := ZambrettiForecast(InHgToHPa(pressure), deltaHPa, windDir, hasWind,
+		now.In(h.cfg.Timezone).Month(), h.cfg.Hemisphere == "south")
+	return letter, text, true
+}
@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+// Copyright (C) 2025 Lenucksi
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestZambrettiForecast(t *testing.T) {
+	tests := []struct {
+		name     string
+		pressure float64
+		delta    float64
+		windDir  float64
+		hasWind  bool
+		month    time.Month
+		southern bool
+		letter   string
+		text     string
+	}{
+		{"high steady winter", 1030, 0.2, 0, false, time.January, false, "A", "Settled fine"},
+		{"low falling winter", 990, -2.5, 0, false, time.January, false, "X", "Rain, very unsettled"},
+		{"rising summer", 1010, 2.0, 0, false, time.July, false, "B", "Fine weather"},
+		{"rising southern summer", 1010, 2.0, 0, false, time.January, true, "B", "Fine weather"},
+		{"steady with southerly wind", 1020, 0, 180, true, time.January, false, "K", "Fairly fine, showers likely"},
+		{"southern hemisphere northerly wind", 1020, 0, 0, true, time.July, true, "K", "Fairly fine, showers likely"},
+		{"below range clamps", 900, -5, 0, false, time.January, false, "Z", "Stormy, much rain"},
+		{"above range clamps", 1100, 0, 0, false, time.January, false, "A", "Settled fine"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			letter, text := ZambrettiForecast(tt.pressure, tt.delta, tt.windDir, tt.hasWind, tt.month, tt.southern)
+			if letter != tt.letter || text != tt.text {
+				t.Errorf("ZambrettiForecast() = %q %q, want %q %q", letter, text, tt.letter, tt.text)
+			}
+		})
+	}
+}
+
+func TestZambrettiForecastHandler(t *testing.T) {
+	h := &WeatherHandler{cfg: &Config{Timezone: time.UTC, Hemisphere: "north"}}
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	// Calm wind ignores the reported direction: 1020 hPa steady would be "K"
+	// with a southerly wind, but "B" without one
+	params := url.Values{"baromin": {"30.12"}, "winddir": {"180"}, "windspeedmph": {"0"}}
+	letter, _, ok := h.zambrettiForecast(params, 0, now)
+	if !ok {
+		t.Fatal("no forecast with pressure present")
+	}
+	if letter != "B" {
+		t.Errorf("letter = %q, want B (wind direction ignored when calm)", letter)
+	}
+
+	if _, _, ok := h.zambrettiForecast(url.Values{}, 0, now); ok {
+		t.Error("forecast reported without pressure")
+	}
+}